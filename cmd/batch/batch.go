@@ -0,0 +1,272 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package batch
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	maxSteps = 100
+)
+
+// step states
+const (
+	StateSucceeded      = "succeeded"
+	StateFailed         = "failed"
+	StateSkipped        = "skipped"
+	StateRolledBack     = "rolled_back"
+	StateRollbackFailed = "rollback_failed"
+	StateNotReversible  = "not_reversible"
+)
+
+// batch states, besides rolled_back and rollback_failed
+const (
+	StateCommitted = "committed"
+
+	// a step registered no compensation, its change stays
+	StatePartiallyRolledBack = "partially_rolled_back"
+)
+
+//Step one operation of a batch. Path is relative to the API prefix the
+//batch is sent to, e.g. /network/networkd/network
+type Step struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+//Batch json request, the steps run in order
+type Batch struct {
+	Steps []Step `json:"steps"`
+}
+
+//StepResult the reply of a step and the compensations undoing it. Error is
+//set when its rollback failed
+type StepResult struct {
+	Method        string          `json:"method"`
+	Path          string          `json:"path"`
+	State         string          `json:"state"`
+	Status        int             `json:"status,omitempty"`
+	Response      json.RawMessage `json:"response,omitempty"`
+	Compensations []string        `json:"compensations,omitempty"`
+	Error         string          `json:"error,omitempty"`
+}
+
+//Result of a batch. Failed is the index of the step that failed
+type Result struct {
+	State  string       `json:"state"`
+	Failed *int         `json:"failed,omitempty"`
+	Steps  []StepResult `json:"steps"`
+}
+
+// batches run one at a time so a rollback only undoes its own changes
+var lock sync.Mutex
+
+// newStepRequest the request of a step, made by the caller of the batch
+func newStepRequest(r *http.Request, prefix string, i int, s Step) (*http.Request, error) {
+	if s.Method == "" || !strings.HasPrefix(s.Path, "/") {
+		return nil, share.NewError(http.StatusBadRequest, "Step %d: method and a path starting with / are required", i)
+	}
+
+	u, err := url.Parse(prefix + s.Path)
+	if err != nil {
+		return nil, share.NewError(http.StatusBadRequest, "Step %d: invalid path '%s'", i, s.Path)
+	}
+
+	if strings.HasPrefix(s.Path, "/batch") {
+		return nil, share.NewError(http.StatusBadRequest, "Step %d: batches can not be nested", i)
+	}
+
+	// the batch is previewed and rolled back as a whole
+	q := u.Query()
+	if q.Get("dry_run") != "" || q.Get("confirm_timeout") != "" {
+		return nil, share.NewError(http.StatusBadRequest, "Step %d: dry_run and confirm_timeout apply to the whole batch", i)
+	}
+
+	req, err := http.NewRequest(strings.ToUpper(s.Method), u.String(), bytes.NewReader(s.Body))
+	if err != nil {
+		return nil, share.NewError(http.StatusBadRequest, "Step %d: %s", i, err)
+	}
+
+	req = req.WithContext(r.Context())
+	req.Header = r.Header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = r.RemoteAddr
+	req.TLS = r.TLS
+
+	return req, nil
+}
+
+// validate matches every step to a route before anything runs
+func validate(root *mux.Router, r *http.Request, prefix string, b *Batch) ([]*http.Request, error) {
+	if len(b.Steps) == 0 || len(b.Steps) > maxSteps {
+		return nil, share.NewError(http.StatusBadRequest, "A batch takes 1 to %d steps", maxSteps)
+	}
+
+	reqs := make([]*http.Request, 0, len(b.Steps))
+	for i, s := range b.Steps {
+		req, err := newStepRequest(r, prefix, i, s)
+		if err != nil {
+			return nil, err
+		}
+
+		var match mux.RouteMatch
+		if !root.Match(req, &match) || match.MatchErr != nil {
+			return nil, share.NewError(http.StatusBadRequest, "Step %d: no route for %s %s", i, req.Method, s.Path)
+		}
+
+		if share.IsDryRun(r) && !previewable(match.Route, req.Method) {
+			return nil, share.NewError(http.StatusNotImplemented, "Step %d: dry run not supported by %s %s", i, req.Method, s.Path)
+		}
+
+		reqs = append(reqs, req)
+	}
+
+	return reqs, nil
+}
+
+func previewable(route *mux.Route, method string) bool {
+	for _, op := range share.RouteOperations(route) {
+		if op.Method == method {
+			return op.DryRun
+		}
+	}
+
+	return false
+}
+
+// response the reply of a step as JSON, a string if it is not
+func response(b *bytes.Buffer) json.RawMessage {
+	if b.Len() == 0 {
+		return nil
+	}
+
+	if json.Valid(b.Bytes()) {
+		return json.RawMessage(bytes.TrimSpace(b.Bytes()))
+	}
+
+	s, _ := json.Marshal(strings.TrimSpace(b.String()))
+
+	return s
+}
+
+// run executes the steps in order through root. On the first failure the
+// compensations of the failed and the completed steps run, last first
+func run(root http.Handler, reqs []*http.Request, b *Batch) (*Result, int) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	result := &Result{State: StateCommitted, Steps: make([]StepResult, len(b.Steps))}
+	txs := make([]*share.Transaction, 0, len(reqs))
+
+	for i, s := range b.Steps {
+		result.Steps[i] = StepResult{Method: reqs[i].Method, Path: s.Path, State: StateSkipped}
+	}
+
+	for i, req := range reqs {
+		t := &share.Transaction{}
+		txs = append(txs, t)
+
+		w := share.NewResponseBuffer()
+		root.ServeHTTP(w, share.WithTransaction(req, t))
+
+		// handlers replying nothing succeeded
+		if w.Status == 0 {
+			w.Status = http.StatusOK
+		}
+
+		step := &result.Steps[i]
+		step.Status = w.Status
+		step.Response = response(&w.Body)
+		step.Compensations = t.Compensations()
+
+		if !w.Failed() {
+			step.State = StateSucceeded
+			continue
+		}
+
+		step.State = StateFailed
+		failed := i
+		result.Failed = &failed
+
+		return result, rollback(result, txs)
+	}
+
+	return result, http.StatusOK
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return false
+	}
+
+	return true
+}
+
+// rollback undoes the steps that ran and replies the status of the failed
+// step, 500 if a change could not be undone
+func rollback(result *Result, txs []*share.Transaction) int {
+	status := result.Steps[*result.Failed].Status
+	result.State = StateRolledBack
+
+	for i := len(txs) - 1; i >= 0; i-- {
+		step := &result.Steps[i]
+
+		// such as restarting a unit, there is nothing to undo it with
+		if i != *result.Failed && isMutating(step.Method) && len(step.Compensations) == 0 {
+			log.Warnf("Step %d %s %s of batch can not be rolled back", i, step.Method, step.Path)
+
+			step.State = StateNotReversible
+			if result.State == StateRolledBack {
+				result.State = StatePartiallyRolledBack
+			}
+			status = http.StatusInternalServerError
+			continue
+		}
+
+		err := txs[i].Rollback()
+		if err != nil {
+			log.Errorf("Failed to roll back step %d %s %s of batch: %s", i, step.Method, step.Path, err)
+
+			step.State = StateRollbackFailed
+			step.Error = err.Error()
+			result.State = StateRollbackFailed
+			status = http.StatusInternalServerError
+			continue
+		}
+
+		// the failed step keeps its state, its partial changes are undone
+		if i != *result.Failed {
+			step.State = StateRolledBack
+		}
+	}
+
+	return status
+}
+
+//Run validates a batch and executes its steps through root under prefix
+func Run(root *mux.Router, r *http.Request, prefix string, b *Batch) (*Result, int, error) {
+	reqs, err := validate(root, r, prefix, b)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result, status := run(root, reqs, b)
+	if result.State != StateCommitted {
+		log.Warnf("Batch failed at step %d and is %s", *result.Failed, result.State)
+	}
+
+	return result, status, nil
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package batch
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+)
+
+func routerBatch(root *mux.Router) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "POST":
+			b := new(Batch)
+
+			err := json.NewDecoder(r.Body).Decode(&b)
+			if err != nil {
+				share.HTTPError(rw, share.BadRequest(err))
+				return
+			}
+
+			// steps are relative to the API prefix the batch is sent to
+			prefix := strings.TrimSuffix(r.URL.Path, "/batch")
+
+			result, status, err := Run(root, r, prefix, b)
+			if err != nil {
+				share.HTTPError(rw, err)
+				return
+			}
+
+			share.JSONResponseStatus(result, status, rw)
+			break
+		default:
+			share.MethodNotAllowed(rw, r)
+		}
+	}
+}
+
+//RegisterRouterBatch register with mux. Steps are dispatched through root
+//so they are authenticated, authorized and audited one by one
+func RegisterRouterBatch(router *mux.Router, root *mux.Router) {
+	share.Describe(router.HandleFunc("/batch", routerBatch(root)),
+		share.Op("POST", "Run operations in order, rolling back the completed ones when one fails", Batch{}, Result{}).Previewable())
+}
@@ -0,0 +1,306 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RestGW/api-routerd/cmd/jobs"
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+)
+
+const testPrefix = "/api/v1"
+
+// stepBody the body of the test routes: undo is the compensation the step
+// registers, fail makes it fail after registering it
+type stepBody struct {
+	Undo string `json:"undo"`
+	Fail bool   `json:"fail"`
+}
+
+// undone the compensations that ran, in the order they ran
+var undone = struct {
+	sync.Mutex
+	names []string
+}{}
+
+func undoneNames() string {
+	undone.Lock()
+	defer undone.Unlock()
+
+	names := strings.Join(undone.names, ",")
+	undone.names = nil
+
+	return names
+}
+
+func change(rw http.ResponseWriter, r *http.Request) {
+	b := new(stepBody)
+
+	err := json.NewDecoder(r.Body).Decode(b)
+	if err != nil {
+		share.HTTPError(rw, share.BadRequest(err))
+		return
+	}
+
+	if b.Undo != "" {
+		share.OnRollback(r, b.Undo, func() error {
+			undone.Lock()
+			defer undone.Unlock()
+
+			undone.names = append(undone.names, b.Undo)
+			return nil
+		})
+	}
+
+	if b.Fail {
+		share.HTTPError(rw, fmt.Errorf("Failed"))
+		return
+	}
+
+	share.JSONResponse(b, rw)
+}
+
+// restart changes something it can not undo
+func restart(rw http.ResponseWriter, r *http.Request) {
+	share.JSONResponse("done", rw)
+}
+
+func job(rw http.ResponseWriter, r *http.Request) {
+	b := new(stepBody)
+	json.NewDecoder(r.Body).Decode(b)
+
+	err := jobs.Run(rw, r, "test job", func() (string, error) {
+		if b.Fail {
+			return "failed", fmt.Errorf("Job failed")
+		}
+
+		return "done", nil
+	})
+	if err != nil {
+		share.HTTPError(rw, err)
+	}
+}
+
+// denySystem stands in for the auth middleware: writes to /system need the
+// admin role
+func denySystem(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, testPrefix+"/system/") && r.Header.Get("X-Test-Role") != "admin" {
+			share.HTTPError(rw, share.NewError(http.StatusForbidden, "Forbidden"))
+			return
+		}
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+func testRouter() *mux.Router {
+	root := mux.NewRouter()
+	root.Use(denySystem)
+
+	s := root.PathPrefix(testPrefix).Subrouter()
+
+	share.Describe(s.HandleFunc("/network/change", change),
+		share.Op("POST", "A change", stepBody{}, stepBody{}).Previewable())
+	share.Describe(s.HandleFunc("/system/change", change),
+		share.Op("POST", "A change needing admin", stepBody{}, stepBody{}).Previewable())
+	share.Describe(s.HandleFunc("/service/restart", restart),
+		share.Op("POST", "A change without inverse", nil, nil))
+	share.Describe(s.HandleFunc("/service/job", job),
+		share.Op("POST", "A change run as a job", stepBody{}, jobs.Job{}))
+
+	RegisterRouterBatch(s, root)
+
+	return root
+}
+
+func step(path string, undo string, fail bool) Step {
+	body, _ := json.Marshal(stepBody{Undo: undo, Fail: fail})
+
+	return Step{Method: "POST", Path: path, Body: body}
+}
+
+func runSteps(t *testing.T, r *http.Request, steps ...Step) (*Result, int) {
+	result, status, err := Run(testRouter(), r, testPrefix, &Batch{Steps: steps})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return result, status
+}
+
+func newRequest() *http.Request {
+	return httptest.NewRequest("POST", testPrefix+"/batch", nil)
+}
+
+func states(result *Result) string {
+	var s []string
+	for _, step := range result.Steps {
+		s = append(s, step.State)
+	}
+
+	return strings.Join(s, ",")
+}
+
+func TestRunCommitted(t *testing.T) {
+	result, status := runSteps(t, newRequest(),
+		step("/network/change", "a", false),
+		step("/network/change", "b", false))
+
+	if status != http.StatusOK || result.State != StateCommitted || result.Failed != nil {
+		t.Fatalf("batch %s %d", result.State, status)
+	}
+
+	if states(result) != "succeeded,succeeded" {
+		t.Errorf("steps %s", states(result))
+	}
+
+	if strings.Join(result.Steps[1].Compensations, ",") != "b" {
+		t.Errorf("compensations %v", result.Steps[1].Compensations)
+	}
+
+	if u := undoneNames(); u != "" {
+		t.Errorf("committed batch undid %s", u)
+	}
+}
+
+func TestRunRollbackOrder(t *testing.T) {
+	result, status := runSteps(t, newRequest(),
+		step("/network/change", "a", false),
+		step("/network/change", "b", false),
+		step("/network/change", "c", true),
+		step("/network/change", "d", false))
+
+	if status != http.StatusInternalServerError || result.State != StateRolledBack {
+		t.Fatalf("batch %s %d", result.State, status)
+	}
+
+	if result.Failed == nil || *result.Failed != 2 {
+		t.Errorf("failed step %v, want 2", result.Failed)
+	}
+
+	if states(result) != "rolled_back,rolled_back,failed,skipped" {
+		t.Errorf("steps %s", states(result))
+	}
+
+	// the partial changes of the failed step first, then the others last first
+	if u := undoneNames(); u != "c,b,a" {
+		t.Errorf("undone in order %s, want c,b,a", u)
+	}
+}
+
+func TestRunNotReversible(t *testing.T) {
+	result, status := runSteps(t, newRequest(),
+		step("/network/change", "a", false),
+		step("/service/restart", "", false),
+		step("/network/change", "", true))
+
+	if status != http.StatusInternalServerError || result.State != StatePartiallyRolledBack {
+		t.Fatalf("batch %s %d, want %s 500", result.State, status, StatePartiallyRolledBack)
+	}
+
+	if states(result) != "rolled_back,not_reversible,failed" {
+		t.Errorf("steps %s", states(result))
+	}
+
+	if u := undoneNames(); u != "a" {
+		t.Errorf("undone %s, want a", u)
+	}
+}
+
+func TestRunWaitsForJobs(t *testing.T) {
+	result, status := runSteps(t, newRequest(),
+		step("/network/change", "a", false),
+		step("/service/job", "", true))
+
+	if status != http.StatusInternalServerError || result.State != StateRolledBack {
+		t.Fatalf("batch %s %d", result.State, status)
+	}
+
+	var j jobs.Job
+	err := json.Unmarshal(result.Steps[1].Response, &j)
+	if err != nil || j.State != jobs.StateFailed {
+		t.Errorf("job step replied '%s': %v", result.Steps[1].Response, err)
+	}
+
+	if u := undoneNames(); u != "a" {
+		t.Errorf("undone %s, want a", u)
+	}
+
+	result, status = runSteps(t, newRequest(), step("/service/job", "", false))
+	if status != http.StatusOK || result.State != StateCommitted {
+		t.Errorf("batch with a job %s %d", result.State, status)
+	}
+}
+
+func TestRunStepAuthorization(t *testing.T) {
+	result, status := runSteps(t, newRequest(),
+		step("/network/change", "a", false),
+		step("/system/change", "b", false))
+
+	if status != http.StatusForbidden || result.State != StateRolledBack {
+		t.Fatalf("batch %s %d, want %s 403", result.State, status, StateRolledBack)
+	}
+
+	if u := undoneNames(); u != "a" {
+		t.Errorf("undone %s, want a", u)
+	}
+
+	r := newRequest()
+	r.Header.Set("X-Test-Role", "admin")
+
+	result, status = runSteps(t, r, step("/system/change", "b", false))
+	if status != http.StatusOK || result.State != StateCommitted {
+		t.Errorf("admin batch %s %d", result.State, status)
+	}
+}
+
+func TestRunNoNesting(t *testing.T) {
+	nested, _ := json.Marshal(Batch{Steps: []Step{step("/network/change", "a", false)}})
+
+	_, _, err := Run(testRouter(), newRequest(), testPrefix, &Batch{Steps: []Step{
+		step("/network/change", "a", false),
+		{Method: "POST", Path: "/batch", Body: nested},
+	}})
+
+	e, ok := err.(*share.Error)
+	if !ok || e.Code != http.StatusBadRequest || !strings.Contains(e.Message, "nested") {
+		t.Errorf("nested batch: %v", err)
+	}
+
+	if u := undoneNames(); u != "" {
+		t.Errorf("refused batch ran steps, undid %s", u)
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	p := &share.Preview{DryRun: true}
+	r := share.WithDryRun(newRequest(), p)
+
+	_, _, err := Run(testRouter(), r, testPrefix, &Batch{Steps: []Step{
+		step("/network/change", "a", false),
+		step("/service/restart", "", false),
+	}})
+
+	e, ok := err.(*share.Error)
+	if !ok || e.Code != http.StatusNotImplemented {
+		t.Errorf("dry run with a step without preview: %v", err)
+	}
+
+	result, status := runSteps(t, r,
+		step("/network/change", "a", false),
+		step("/network/change", "b", false))
+
+	if status != http.StatusOK || result.State != StateCommitted {
+		t.Errorf("previewable dry run batch %s %d", result.State, status)
+	}
+}
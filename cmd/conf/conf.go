@@ -4,6 +4,12 @@ package conf
 
 import (
 	"flag"
+	"fmt"
+	"net"
+	"path"
+	"reflect"
+	"sync/atomic"
+	"time"
 
 	"github.com/RestGW/api-routerd/cmd/share"
 
@@ -13,28 +19,360 @@ import (
 
 // App Version
 const (
-	Version  = "0.1"
-	ConfPath = "/etc/api-routerd"
-	ConfFile = "api-routerd"
-	TLSCert  = "tls/server.crt"
-	TLSKey   = "tls/server.key"
+	Version     = "0.1"
+	ConfPath    = "/etc/api-routerd"
+	ConfFile    = "api-routerd"
+	TLSCert     = "tls/server.crt"
+	TLSKey      = "tls/server.key"
+	TLSClientCA = "tls/ca.crt"
+	AuthFile    = "api-routerd-auth.conf"
+	AuditFile   = "/var/log/api-router/audit.log"
+	PluginsDir  = "plugins"
+	ExtDir      = "ext"
+
+	// date after which the unversioned /api paths may be removed
+	LegacySunset = "2027-12-31"
+)
+
+// Auth modes
+const (
+	// session tokens, client certificates and Unix socket peers
+	AuthModeAny = "any"
+
+	// only session tokens
+	AuthModeToken = "token"
+
+	// only client certificates and Unix socket peers
+	AuthModeCertificate = "certificate"
 )
 
 // flag
 var (
-	IPFlag   string
-	PortFlag string
+	IPFlag         string
+	PortFlag       string
+	UnixSocketFlag string
 )
 
+// HealthChecks the dependencies /api/health reports on
+var HealthChecks = []string{"dbus", "systemd", "networkd", "firewalld", "machined", "netlink", "etc"}
+
+//Settings the applied configuration. A reload publishes new Settings and
+//never changes the current ones, so requests read them without a lock
+type Settings struct {
+	IPAddress  string
+	Port       string
+	UnixSocket string
+	Listeners  []Listen
+	Audit      AuditLog
+	TLS        TLSConf
+	Auth       AuthConf
+	Log        share.LogConfig
+	API        APIConf
+	Health     HealthConf
+	Backup     share.BackupConfig
+	History    share.HistoryConfig
+
+	// modules switched off in [Modules]
+	disabled map[string]bool
+}
+
+var settings atomic.Value
+
+//Current the applied settings
+func Current() *Settings {
+	return settings.Load().(*Settings)
+}
+
+// defaultSettings the settings without a conf file, from the flags
+func defaultSettings() *Settings {
+	return &Settings{
+		IPAddress:  IPFlag,
+		Port:       PortFlag,
+		UnixSocket: UnixSocketFlag,
+		Audit:      AuditLog{File: AuditFile},
+		TLS:        defaultTLS(),
+		Auth:       defaultAuth(),
+		API:        APIConf{Sunset: LegacySunset},
+		Health:     defaultHealth(),
+		Backup:     share.DefaultBackupConfig(),
+		History:    share.DefaultHistoryConfig(),
+		disabled:   make(map[string]bool),
+	}
+}
+
 //Config config file key value
 type Config struct {
-	Server Network `mapstructure:"Network"`
+	Server  Network             `mapstructure:"Network"`
+	Audit   AuditLog            `mapstructure:"Audit"`
+	TLS     TLSConf             `mapstructure:"TLS"`
+	Auth    AuthConf            `mapstructure:"Auth"`
+	Log     share.LogConfig     `mapstructure:"Log"`
+	API     APIConf             `mapstructure:"API"`
+	Health  HealthConf          `mapstructure:"Health"`
+	Backup  share.BackupConfig  `mapstructure:"Backup"`
+	History share.HistoryConfig `mapstructure:"History"`
+	Modules map[string]bool     `mapstructure:"Modules"`
+}
+
+//TLSConf server certificate, key, client CA bundle and minimum TLS version.
+//Relative paths are below ConfPath
+type TLSConf struct {
+	Cert       string
+	Key        string
+	ClientCA   string
+	MinVersion string
+}
+
+//APIConf sunset date (YYYY-MM-DD) announced on the unversioned /api paths
+//and the browser origins besides the server's own that may open WebSockets
+type APIConf struct {
+	Sunset  string
+	Origins []string
+}
+
+//HealthConf checks that must pass for api-routerd to be healthy. The
+//others only degrade it
+type HealthConf struct {
+	Required []string
+}
+
+//AuthConf auth file and which credentials are accepted
+type AuthConf struct {
+	File string
+	Mode string
 }
 
-//Network IP Address and Port
+//Network IP Address, Port and local Unix socket
 type Network struct {
-	IPAddress string
-	Port      string
+	IPAddress  string
+	Port       string
+	UnixSocket string
+	Listen     []Listen
+}
+
+//Listen a listener given by IP:port or by socket activation FileDescriptorName
+type Listen struct {
+	Address string
+	Name    string
+	TLS     bool
+}
+
+//AuditLog file of the audit events and whether they are sent to the journal too
+type AuditLog struct {
+	File    string
+	Journal bool
+}
+
+func defaultTLS() TLSConf {
+	return TLSConf{
+		Cert:       path.Join(ConfPath, TLSCert),
+		Key:        path.Join(ConfPath, TLSKey),
+		ClientCA:   path.Join(ConfPath, TLSClientCA),
+		MinVersion: "1.2",
+	}
+}
+
+func defaultAuth() AuthConf {
+	return AuthConf{
+		File: path.Join(ConfPath, AuthFile),
+		Mode: AuthModeAny,
+	}
+}
+
+func confPath(p string, def string) string {
+	if p == "" {
+		return def
+	}
+
+	if !path.IsAbs(p) {
+		return path.Join(ConfPath, p)
+	}
+
+	return p
+}
+
+func (t *TLSConf) validate() error {
+	def := defaultTLS()
+
+	t.Cert = confPath(t.Cert, def.Cert)
+	t.Key = confPath(t.Key, def.Key)
+	t.ClientCA = confPath(t.ClientCA, def.ClientCA)
+
+	if t.MinVersion == "" {
+		t.MinVersion = def.MinVersion
+	}
+
+	switch t.MinVersion {
+	case "1.0", "1.1", "1.2", "1.3":
+		return nil
+	}
+
+	return fmt.Errorf("Unsupported TLS MinVersion '%s'", t.MinVersion)
+}
+
+func (a *AuthConf) validate() error {
+	def := defaultAuth()
+
+	a.File = confPath(a.File, def.File)
+
+	if a.Mode == "" {
+		a.Mode = def.Mode
+	}
+
+	switch a.Mode {
+	case AuthModeAny, AuthModeToken, AuthModeCertificate:
+		return nil
+	}
+
+	return fmt.Errorf("Unsupported auth Mode '%s'", a.Mode)
+}
+
+func (a *APIConf) validate() error {
+	if a.Sunset == "" {
+		a.Sunset = LegacySunset
+	}
+
+	_, err := time.Parse("2006-01-02", a.Sunset)
+	if err != nil {
+		return fmt.Errorf("Failed to parse Sunset '%s': %s", a.Sunset, err)
+	}
+
+	return nil
+}
+
+func defaultHealth() HealthConf {
+	return HealthConf{Required: []string{"dbus", "systemd", "netlink", "etc"}}
+}
+
+func (h *HealthConf) validate() error {
+	if len(h.Required) == 0 {
+		*h = defaultHealth()
+		return nil
+	}
+
+	for _, c := range h.Required {
+		known := false
+		for _, k := range HealthChecks {
+			if c == k {
+				known = true
+			}
+		}
+
+		if !known {
+			return fmt.Errorf("Unknown health check '%s'", c)
+		}
+	}
+
+	return nil
+}
+
+//HealthRequired whether check must pass
+func HealthRequired(check string) bool {
+	for _, c := range Current().Health.Required {
+		if c == check {
+			return true
+		}
+	}
+
+	return false
+}
+
+func validateLog(l *share.LogConfig) error {
+	if l.Level != "" {
+		_, err := log.ParseLevel(l.Level)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch l.Format {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("Unsupported log Format '%s'", l.Format)
+	}
+
+	if l.MaxSize < 0 || l.MaxBackups < 0 {
+		return fmt.Errorf("Log MaxSize and MaxBackups must not be negative")
+	}
+
+	return nil
+}
+
+func validateBackup(b *share.BackupConfig) error {
+	def := share.DefaultBackupConfig()
+
+	if b.Dir == "" {
+		b.Dir = def.Dir
+	}
+
+	if !path.IsAbs(b.Dir) {
+		return fmt.Errorf("Backup Dir '%s' must be absolute", b.Dir)
+	}
+
+	if !viper.IsSet("Backup.Keep") {
+		b.Keep = def.Keep
+	}
+
+	if b.Keep < 0 {
+		return fmt.Errorf("Backup Keep must not be negative")
+	}
+
+	return nil
+}
+
+func validateHistory(h *share.HistoryConfig) error {
+	def := share.DefaultHistoryConfig()
+
+	if h.Dir == "" {
+		h.Dir = def.Dir
+	}
+
+	if !path.IsAbs(h.Dir) {
+		return fmt.Errorf("History Dir '%s' must be absolute", h.Dir)
+	}
+
+	if !viper.IsSet("History.Keep") {
+		h.Keep = def.Keep
+	}
+
+	if h.Keep < 0 {
+		return fmt.Errorf("History Keep must not be negative")
+	}
+
+	return nil
+}
+
+func validateModules(modules map[string]bool) error {
+	for m := range modules {
+		if !knownModules.Contains(m) {
+			return fmt.Errorf("Unknown module '%s'", m)
+		}
+	}
+
+	return nil
+}
+
+func (l *Listen) validate() error {
+	if l.Address == "" {
+		if l.Name == "" {
+			return fmt.Errorf("Listen needs Address or Name")
+		}
+
+		return nil
+	}
+
+	host, port, err := net.SplitHostPort(l.Address)
+	if err != nil {
+		return err
+	}
+
+	if host != "" && net.ParseIP(host) == nil {
+		return fmt.Errorf("Failed to parse IP address '%s'", host)
+	}
+
+	_, err = share.ParsePort(port)
+
+	return err
 }
 
 func init() {
@@ -45,6 +383,9 @@ func init() {
 
 	flag.StringVar(&IPFlag, "ip", defaultIP, "The server IP address.")
 	flag.StringVar(&PortFlag, "port", defaultPort, "The server port.")
+	flag.StringVar(&UnixSocketFlag, "unix-socket", "", "The local Unix socket path.")
+
+	settings.Store(defaultSettings())
 }
 
 func parseConfFile() (Config, error) {
@@ -56,11 +397,13 @@ func parseConfFile() (Config, error) {
 	err := viper.ReadInConfig()
 	if err != nil {
 		log.Errorf("Faild to parse  config file, %v", err)
+		return conf, err
 	}
 
 	err = viper.Unmarshal(&conf)
 	if err != nil {
 		log.Errorf("Failed to decode config into struct, %v", err)
+		return conf, err
 	}
 
 	_, err = share.ParseIP(conf.Server.IPAddress)
@@ -75,11 +418,99 @@ func parseConfFile() (Config, error) {
 		return conf, err
 	}
 
+	for i := range conf.Server.Listen {
+		err = conf.Server.Listen[i].validate()
+		if err != nil {
+			log.Errorf("Failed to parse conf file Listen=%+v: %s", conf.Server.Listen[i], err)
+			return conf, err
+		}
+	}
+
+	err = conf.TLS.validate()
+	if err != nil {
+		log.Errorf("Failed to parse conf file [TLS]: %s", err)
+		return conf, err
+	}
+
+	err = conf.Auth.validate()
+	if err != nil {
+		log.Errorf("Failed to parse conf file [Auth]: %s", err)
+		return conf, err
+	}
+
+	err = conf.API.validate()
+	if err != nil {
+		log.Errorf("Failed to parse conf file [API]: %s", err)
+		return conf, err
+	}
+
+	err = conf.Health.validate()
+	if err != nil {
+		log.Errorf("Failed to parse conf file [Health]: %s", err)
+		return conf, err
+	}
+
+	err = validateLog(&conf.Log)
+	if err != nil {
+		log.Errorf("Failed to parse conf file [Log]: %s", err)
+		return conf, err
+	}
+
+	err = validateBackup(&conf.Backup)
+	if err != nil {
+		log.Errorf("Failed to parse conf file [Backup]: %s", err)
+		return conf, err
+	}
+
+	err = validateHistory(&conf.History)
+	if err != nil {
+		log.Errorf("Failed to parse conf file [History]: %s", err)
+		return conf, err
+	}
+
+	err = validateModules(conf.Modules)
+	if err != nil {
+		log.Errorf("Failed to parse conf file [Modules]: %s", err)
+		return conf, err
+	}
+
 	log.Debugf("Conf file: Parsed IPAddress=%s and Port=%s", conf.Server.IPAddress, conf.Server.Port)
 
 	return conf, nil
 }
 
+// applyConf publishes the settings of conf and returns the ones it replaced
+func applyConf(conf *Config) *Settings {
+	n := &Settings{
+		IPAddress:  conf.Server.IPAddress,
+		Port:       conf.Server.Port,
+		UnixSocket: conf.Server.UnixSocket,
+		Listeners:  conf.Server.Listen,
+		Audit:      AuditLog{File: AuditFile, Journal: conf.Audit.Journal},
+		TLS:        conf.TLS,
+		Auth:       conf.Auth,
+		Log:        conf.Log,
+		API:        conf.API,
+		Health:     conf.Health,
+		Backup:     conf.Backup,
+		History:    conf.History,
+		disabled:   disabledModules(conf.Modules),
+	}
+
+	if conf.Audit.File != "" {
+		n.Audit.File = conf.Audit.File
+	}
+
+	share.SetBackupConfig(n.Backup)
+	share.SetHistoryConfig(n.History)
+	share.SetWebSocketOrigins(n.API.Origins)
+
+	old := Current()
+	settings.Store(n)
+
+	return old
+}
+
 // InitConf Init the config from conf file
 func InitConf() error {
 
@@ -87,9 +518,37 @@ func InitConf() error {
 	if err != nil {
 		log.Fatalf("Failed to read conf file of '%s'. Using defaults: %v", ConfFile, err)
 		flag.Parse()
+		settings.Store(defaultSettings())
 	} else {
-		IPFlag = conf.Server.IPAddress
-		PortFlag = conf.Server.Port
+		applyConf(&conf)
+	}
+
+	return nil
+}
+
+// ReloadConf re-reads the conf file. An invalid file is rejected and the last good config stays active.
+// Sections only read at startup are logged when they changed
+func ReloadConf() error {
+	conf, err := parseConfFile()
+	if err != nil {
+		return err
+	}
+
+	old := applyConf(&conf)
+	n := Current()
+
+	for _, c := range []struct {
+		section string
+		changed bool
+	}{
+		{"Modules", !reflect.DeepEqual(old.disabled, n.disabled)},
+		{"TLS", old.TLS != n.TLS},
+		{"Log", old.Log != n.Log},
+		{"Audit", old.Audit != n.Audit},
+	} {
+		if c.changed {
+			log.Warnf("Changes to [%s] need a restart of api-routerd to apply", c.section)
+		}
 	}
 
 	return nil
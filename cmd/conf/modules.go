@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package conf
+
+import (
+	"github.com/RestGW/api-routerd/cmd/share"
+)
+
+// modules that can be switched off in [Modules]
+var knownModules *share.Set
+
+func init() {
+	knownModules = share.NewSet()
+
+	for _, m := range []string{
+		"container", "machine",
+		"network", "netlink", "networkd", "networkctl", "ethtool",
+		"proc",
+		"systemd",
+		"system", "hostname", "timedate", "kmod", "group", "user", "sysctl", "login", "firewalld",
+		"plugins", "ext",
+		"metrics",
+	} {
+		knownModules.Add(m)
+	}
+}
+
+// disabledModules the modules set to false in [Modules]
+func disabledModules(modules map[string]bool) map[string]bool {
+	disabled := make(map[string]bool)
+
+	for m, enabled := range modules {
+		if !enabled {
+			disabled[m] = true
+		}
+	}
+
+	return disabled
+}
+
+//ModuleEnabled whether a module is mounted. Modules are enabled unless set to false in [Modules]
+func ModuleEnabled(name string) bool {
+	return !Current().disabled[name]
+}
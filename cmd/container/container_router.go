@@ -3,6 +3,7 @@
 package container
 
 import (
+	"github.com/RestGW/api-routerd/cmd/conf"
 	"github.com/RestGW/api-routerd/cmd/container/machine"
 	"github.com/gorilla/mux"
 )
@@ -11,6 +12,8 @@ import (
 func RegisterRouterContainer(router *mux.Router) {
 	n := router.PathPrefix("/container").Subrouter()
 
-	machine.InitMachine()
-	machine.RegisterRouterMachine(n)
+	if conf.ModuleEnabled("machine") {
+		machine.InitMachine()
+		machine.RegisterRouterMachine(n)
+	}
 }
@@ -31,7 +31,7 @@ func (m *Machine) MethodGet(rw http.ResponseWriter) error {
 
 	b := machineMethods.Contains(m.Path)
 	if !b {
-		return fmt.Errorf("Failed to call method machine: %s not found", m.Path)
+		return share.NotFound(fmt.Errorf("Failed to call method machine: %s not found", m.Path))
 	}
 
 	switch m.Path {
@@ -113,7 +113,7 @@ func (m *Machine) MethodConfigure(rw http.ResponseWriter) error {
 
 	b := machineMethods.Contains(m.Path)
 	if !b {
-		return fmt.Errorf("Failed to call method machine: %s not found", m.Path)
+		return share.NotFound(fmt.Errorf("Failed to call method machine: %s not found", m.Path))
 	}
 
 	switch m.Path {
@@ -123,13 +123,6 @@ func (m *Machine) MethodConfigure(rw http.ResponseWriter) error {
 			return err
 		}
 
-		return nil
-	case "clone-image":
-		err := CloneImage(m.Old, m.New)
-		if err != nil {
-			return err
-		}
-
 		return nil
 	case "rename-image":
 		err := RenameImage(m.Old, m.New)
@@ -150,6 +143,16 @@ func (m *Machine) MethodConfigure(rw http.ResponseWriter) error {
 	return nil
 }
 
+//Clone clone the image m.Old to m.New. Runs as a job
+func (m *Machine) Clone() (string, error) {
+	err := CloneImage(m.Old, m.New)
+	if err != nil {
+		return "", err
+	}
+
+	return "done", nil
+}
+
 //InitMachine init machine package
 func InitMachine() error {
 	machineMethods = share.NewSet()
@@ -71,8 +71,7 @@ func CloneImage(image string, newImage string) error {
 	defer conn.Close()
 
 	r := conn.object.Call(fmt.Sprintf("%s.%s", dbusInterface, "CloneImage"), 0, image, newImage, false)
-	if r != nil {
-		fmt.Println(r)
+	if r.Err != nil {
 		return fmt.Errorf("Failed to clone image: %v", r.Err)
 	}
 
@@ -6,6 +6,9 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/RestGW/api-routerd/cmd/jobs"
+	"github.com/RestGW/api-routerd/cmd/share"
+
 	"github.com/gorilla/mux"
 )
 
@@ -24,9 +27,11 @@ func routerMachineGet(rw http.ResponseWriter, r *http.Request) {
 
 		err := m.MethodGet(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -41,18 +46,25 @@ func routerMachineConfigure(rw http.ResponseWriter, r *http.Request) {
 		m := new(Machine)
 		err := json.NewDecoder(r.Body).Decode(&m)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
 		m.Path = path
 		m.Property = property
 
-		err = m.MethodConfigure(rw)
+		// copying an image takes a while
+		if path == "clone-image" {
+			err = jobs.Run(rw, r, "clone image "+m.Old+" to "+m.New, m.Clone)
+		} else {
+			err = m.MethodConfigure(rw)
+		}
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -60,7 +72,10 @@ func routerMachineConfigure(rw http.ResponseWriter, r *http.Request) {
 func RegisterRouterMachine(n *mux.Router) {
 	m := n.PathPrefix("/machine").Subrouter().StrictSlash(false)
 
-	m.HandleFunc("/list/{command}", routerMachineGet)
-	m.HandleFunc("/get/{command}/{property}", routerMachineGet)
-	m.HandleFunc("/configure/{command}/{property}", routerMachineConfigure)
+	share.Describe(m.HandleFunc("/list/{command}", routerMachineGet),
+		share.Op("GET", "List machines or images", nil, []map[string]interface{}{}))
+	share.Describe(m.HandleFunc("/get/{command}/{property}", routerMachineGet),
+		share.Op("GET", "A property of a machine or image", nil, map[string]interface{}{}))
+	share.Describe(m.HandleFunc("/configure/{command}/{property}", routerMachineConfigure),
+		share.Op("POST", "Terminate a machine or clone, rename or remove an image. clone-image replies 202 with a job", Machine{}, jobs.Job{}))
 }
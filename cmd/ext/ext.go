@@ -0,0 +1,556 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package ext
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	handshakeTimeout = 10 * time.Second
+	requestTimeout   = 30 * time.Second
+
+	// restart delay doubles after each crash up to restartMax
+	restartMin = time.Second
+	restartMax = time.Minute
+)
+
+// plugin states
+const (
+	stateStarting = "starting"
+	stateRunning  = "running"
+	stateFailed   = "failed"
+	stateStopped  = "stopped"
+)
+
+//Status of a plugin as listed by /api/ext
+type Status struct {
+	Name     string  `json:"name"`
+	Version  string  `json:"version"`
+	Path     string  `json:"path"`
+	State    string  `json:"state"`
+	PID      int     `json:"pid,omitempty"`
+	Restarts int     `json:"restarts"`
+	Routes   []Route `json:"routes"`
+	Error    string  `json:"error,omitempty"`
+}
+
+//Plugin an executable plugin and the process currently serving it
+type Plugin struct {
+	Name string
+	Path string
+
+	lock     sync.Mutex
+	state    string
+	version  string
+	routes   []Route
+	restarts int
+	err      string
+	cmd      *exec.Cmd
+
+	// routes of the last handshake, rebuilt on every restart
+	router *mux.Router
+
+	// requests waiting for a response, keyed by ID
+	pending map[uint64]chan *Response
+	nextID  uint64
+
+	// set under lock, writes are serialized by writeLock
+	writeLock sync.Mutex
+	out       requestWriter
+	enc       *json.Encoder
+
+	// why the plugin was killed, reported once it exited
+	failure string
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// requestWriter the pipe or socket requests are written to. Both take a
+// deadline, so a plugin that stops reading can not block us
+type requestWriter interface {
+	io.Writer
+	SetWriteDeadline(t time.Time) error
+}
+
+var plugins = struct {
+	sync.RWMutex
+	list []*Plugin
+}{}
+
+func newPlugin(file string) *Plugin {
+	name := path.Base(file)
+
+	return &Plugin{
+		Name:    strings.TrimSuffix(name, path.Ext(name)),
+		Path:    file,
+		state:   stateStarting,
+		pending: make(map[uint64]chan *Response),
+		stop:    make(chan struct{}),
+	}
+}
+
+func (p *Plugin) status() Status {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	s := Status{
+		Name:     p.Name,
+		Version:  p.version,
+		Path:     p.Path,
+		State:    p.state,
+		Restarts: p.restarts,
+		Routes:   p.routes,
+		Error:    p.err,
+	}
+
+	if p.state == stateRunning && p.cmd != nil && p.cmd.Process != nil {
+		s.PID = p.cmd.Process.Pid
+	}
+
+	if s.Routes == nil {
+		s.Routes = []Route{}
+	}
+
+	return s
+}
+
+// logLines forwards the output of the plugin to our log
+func (p *Plugin) logLines(r io.Reader) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			continue
+		}
+
+		log.Infof("ext %s: %s", p.Name, line)
+	}
+}
+
+func readHandshake(dec *json.Decoder) (*Handshake, error) {
+	done := make(chan error, 1)
+	hs := new(Handshake)
+
+	go func() {
+		done <- dec.Decode(hs)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read handshake: %s", err)
+		}
+	case <-time.After(handshakeTimeout):
+		return nil, fmt.Errorf("No handshake within %s", handshakeTimeout)
+	}
+
+	if hs.Protocol != ProtocolVersion {
+		return nil, fmt.Errorf("Unsupported protocol %d, expected %d", hs.Protocol, ProtocolVersion)
+	}
+
+	for _, r := range hs.Routes {
+		if r.Method == "" || !strings.HasPrefix(r.Path, "/") {
+			return nil, fmt.Errorf("Invalid route %s '%s'", r.Method, r.Path)
+		}
+	}
+
+	return hs, nil
+}
+
+func (p *Plugin) newRouter(routes []Route) *mux.Router {
+	r := mux.NewRouter()
+	r.NotFoundHandler = http.HandlerFunc(share.RouteNotFound)
+	r.MethodNotAllowedHandler = http.HandlerFunc(share.MethodNotAllowed)
+
+	for _, route := range routes {
+		r.HandleFunc(route.Path, p.forward(route)).Methods(route.Method)
+	}
+
+	return r
+}
+
+// serve starts the executable, reads its handshake and relays responses
+// until it exits
+func (p *Plugin) serve() error {
+	cmd := exec.Command(p.Path)
+	cmd.Dir = path.Dir(p.Path)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("API_ROUTERD_EXT_PROTOCOL=%d", ProtocolVersion),
+		"API_ROUTERD_EXT_NAME="+p.Name)
+
+	// do not outlive api-routerd
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGTERM}
+
+	// a pipe of our own rather than StdinPipe, for its write deadline
+	stdinReader, stdin, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer stdinReader.Close()
+	defer stdin.Close()
+
+	cmd.Stdin = stdinReader
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	// the plugin holds its own copy
+	stdinReader.Close()
+
+	p.lock.Lock()
+	p.cmd = cmd
+	p.lock.Unlock()
+
+	// all reads from the pipes must be done before Wait
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		p.logLines(stderr)
+		readers.Done()
+	}()
+
+	wait := func() error {
+		readers.Wait()
+		return cmd.Wait()
+	}
+
+	dec := json.NewDecoder(stdout)
+	hs, err := readHandshake(dec)
+	if err != nil {
+		cmd.Process.Kill()
+		wait()
+		return err
+	}
+
+	var out requestWriter = stdin
+	var conn net.Conn
+
+	if hs.Socket != "" {
+		conn, err = net.DialTimeout("unix", hs.Socket, handshakeTimeout)
+		if err != nil {
+			cmd.Process.Kill()
+			wait()
+			return fmt.Errorf("Failed to connect to %s: %s", hs.Socket, err)
+		}
+		defer conn.Close()
+
+		out = conn
+
+		// whatever the plugin still prints is logged
+		rest := io.MultiReader(dec.Buffered(), stdout)
+
+		readers.Add(1)
+		go func() {
+			p.logLines(rest)
+			readers.Done()
+		}()
+
+		dec = json.NewDecoder(conn)
+	}
+
+	p.running(out, hs)
+
+	log.Infof("Started ext plugin %s version '%s' with %d routes", p.Name, hs.Version, len(hs.Routes))
+
+	p.relay(dec)
+
+	if conn != nil {
+		conn.Close()
+	} else {
+		stdin.Close()
+	}
+
+	cmd.Process.Kill()
+
+	return wait()
+}
+
+// running accepts requests to the routes of the handshake
+func (p *Plugin) running(out requestWriter, hs *Handshake) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// requests are accepted once running, so the encoder must be set first
+	p.out = out
+	p.enc = json.NewEncoder(out)
+	p.state = stateRunning
+	p.version = hs.Version
+	p.routes = hs.Routes
+	p.router = p.newRouter(hs.Routes)
+	p.err = ""
+	p.failure = ""
+}
+
+// relay hands the responses to the waiting requests; ends when the plugin
+// closes stdout or the socket
+func (p *Plugin) relay(dec *json.Decoder) {
+	for {
+		resp := new(Response)
+		err := dec.Decode(resp)
+		if err != nil {
+			if err != io.EOF {
+				log.Errorf("Failed to read from ext plugin %s: %s", p.Name, err)
+			}
+			break
+		}
+
+		p.lock.Lock()
+		ch, ok := p.pending[resp.ID]
+		delete(p.pending, resp.ID)
+		p.lock.Unlock()
+
+		if !ok {
+			log.Errorf("Ext plugin %s replied to unknown request %d", p.Name, resp.ID)
+			continue
+		}
+
+		ch <- resp
+	}
+}
+
+// kill fails a plugin that stopped reading requests, run restarts it. enc
+// is the encoder the request was written with, a restarted plugin is spared
+func (p *Plugin) kill(enc *json.Encoder, reason string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.enc != enc || p.state != stateRunning {
+		return
+	}
+
+	log.Errorf("Ext plugin %s %s. Restarting it", p.Name, reason)
+
+	p.state = stateFailed
+	p.failure = reason
+
+	if p.cmd != nil && p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+}
+
+// exited fails the requests still waiting on the process that exited
+func (p *Plugin) exited(err error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for id, ch := range p.pending {
+		close(ch)
+		delete(p.pending, id)
+	}
+
+	p.cmd = nil
+
+	select {
+	case <-p.stop:
+		p.state = stateStopped
+		return
+	default:
+	}
+
+	p.state = stateFailed
+	switch {
+	case p.failure != "":
+		p.err = p.failure
+	case err != nil:
+		p.err = err.Error()
+	default:
+		p.err = "Exited"
+	}
+}
+
+// run keeps the plugin running and restarts it after a crash
+func (p *Plugin) run() {
+	delay := restartMin
+
+	for {
+		started := time.Now()
+
+		err := p.serve()
+		p.exited(err)
+
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+
+		log.Errorf("Ext plugin %s exited: %v. Restarting in %s", p.Name, err, delay)
+
+		// a plugin that ran for a while starts over with a short delay
+		if time.Since(started) > restartMax {
+			delay = restartMin
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > restartMax {
+			delay = restartMax
+		}
+
+		p.lock.Lock()
+		p.restarts++
+		p.state = stateStarting
+		p.lock.Unlock()
+	}
+}
+
+// call sends the request and waits for its response
+func (p *Plugin) call(req *Request) (*Response, error) {
+	p.lock.Lock()
+	if p.state != stateRunning {
+		state := p.state
+		p.lock.Unlock()
+
+		return nil, share.NewError(http.StatusServiceUnavailable, "Ext plugin '%s' is %s", p.Name, state)
+	}
+
+	p.nextID++
+	req.ID = p.nextID
+
+	ch := make(chan *Response, 1)
+	p.pending[req.ID] = ch
+	out := p.out
+	enc := p.enc
+	p.lock.Unlock()
+
+	cancel := func() {
+		p.lock.Lock()
+		delete(p.pending, req.ID)
+		p.lock.Unlock()
+	}
+
+	// the wait for a plugin that stopped reading ends with the deadline
+	p.writeLock.Lock()
+	out.SetWriteDeadline(time.Now().Add(requestTimeout))
+	err := enc.Encode(req)
+	p.writeLock.Unlock()
+	if err != nil {
+		cancel()
+
+		if os.IsTimeout(err) {
+			p.kill(enc, fmt.Sprintf("did not read a request within %s", requestTimeout))
+			return nil, share.NewError(http.StatusGatewayTimeout, "Ext plugin '%s' did not read the request within %s", p.Name, requestTimeout)
+		}
+
+		return nil, share.NewError(http.StatusBadGateway, "Failed to send request to ext plugin '%s': %s", p.Name, err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, share.NewError(http.StatusBadGateway, "Ext plugin '%s' exited", p.Name)
+		}
+
+		return resp, nil
+	case <-time.After(requestTimeout):
+		cancel()
+		return nil, share.NewError(http.StatusGatewayTimeout, "Ext plugin '%s' did not reply within %s", p.Name, requestTimeout)
+	}
+}
+
+// Stop terminates the plugin and does not restart it
+func (p *Plugin) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.cmd != nil && p.cmd.Process != nil {
+		p.cmd.Process.Signal(syscall.SIGTERM)
+	}
+}
+
+func isExecutable(f os.FileInfo) bool {
+	return f.Mode().IsRegular() && f.Mode()&0111 != 0
+}
+
+//Load starts every executable in dir and keeps it running
+func Load(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var list []*Plugin
+	for _, f := range files {
+		if !isExecutable(f) {
+			continue
+		}
+
+		p := newPlugin(path.Join(dir, f.Name()))
+		list = append(list, p)
+
+		go p.run()
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	plugins.Lock()
+	plugins.list = list
+	plugins.Unlock()
+
+	return nil
+}
+
+//Stop terminates all plugins
+func Stop() {
+	plugins.RLock()
+	defer plugins.RUnlock()
+
+	for _, p := range plugins.list {
+		p.Stop()
+	}
+}
+
+//List the status of all plugins
+func List() []Status {
+	plugins.RLock()
+	defer plugins.RUnlock()
+
+	list := make([]Status, 0, len(plugins.list))
+	for _, p := range plugins.list {
+		list = append(list, p.status())
+	}
+
+	return list
+}
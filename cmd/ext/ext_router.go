@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package ext
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	maxBodySize = 1 << 20
+)
+
+func routerGetExt(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		err := share.JSONResponse(List(), rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+// forward relays a request to a route of the plugin and replies its response
+func (p *Plugin) forward(route Route) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+		if err != nil {
+			share.HTTPError(rw, share.BadRequest(err))
+			return
+		}
+
+		if len(body) > maxBodySize {
+			share.HTTPError(rw, share.NewError(http.StatusRequestEntityTooLarge, "Request body exceeds %d bytes", maxBodySize))
+			return
+		}
+
+		if len(body) > 0 && !json.Valid(body) {
+			share.HTTPError(rw, share.BadRequest(fmt.Errorf("Request body is not JSON")))
+			return
+		}
+
+		req := &Request{
+			Method:   r.Method,
+			Path:     r.URL.Path,
+			Route:    route.Path,
+			Vars:     mux.Vars(r),
+			Query:    r.URL.Query(),
+			Body:     body,
+			Identity: share.RequestIdentity(r),
+		}
+
+		resp, err := p.call(req)
+		if err != nil {
+			log.Errorf("Failed to call ext plugin %s: %s", p.Name, err)
+			share.HTTPError(rw, err)
+			return
+		}
+
+		// a status net/http can not write is a faulty plugin
+		if resp.Status != 0 && (resp.Status < 100 || resp.Status > 599) {
+			log.Errorf("Ext plugin %s replied invalid status %d", p.Name, resp.Status)
+			share.HTTPError(rw, share.NewError(http.StatusBadGateway, "Ext plugin '%s' replied invalid status %d", p.Name, resp.Status))
+			return
+		}
+
+		if resp.Error != "" {
+			code := resp.Status
+			if code == 0 {
+				code = http.StatusInternalServerError
+			}
+
+			share.HTTPError(rw, share.NewError(code, "%s", resp.Error))
+			return
+		}
+
+		code := resp.Status
+		if code == 0 {
+			code = http.StatusOK
+		}
+
+		if len(resp.Body) == 0 {
+			rw.WriteHeader(code)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(code)
+		rw.Write(resp.Body)
+	}
+}
+
+// ServeHTTP routes a request with the routes of the last handshake
+func (p *Plugin) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	p.lock.Lock()
+	router := p.router
+	state := p.state
+	p.lock.Unlock()
+
+	if router == nil {
+		share.HTTPError(rw, share.NewError(http.StatusServiceUnavailable, "Ext plugin '%s' is %s", p.Name, state))
+		return
+	}
+
+	router.ServeHTTP(rw, r)
+}
+
+//RegisterRouterExt register the executable plugins and their status with mux
+func RegisterRouterExt(router *mux.Router) {
+	n := router.PathPrefix("/ext").Subrouter().StrictSlash(false)
+
+	share.Describe(n.HandleFunc("", routerGetExt),
+		share.Op("GET", "List the executable plugins with their state and routes", nil, []Status{}))
+
+	plugins.RLock()
+	defer plugins.RUnlock()
+
+	for _, p := range plugins.list {
+		route := n.PathPrefix("/" + p.Name)
+		route.Handler(stripPrefix(route, p))
+	}
+}
+
+// stripPrefix strips the path the prefix route matched, as the plugin routes
+// are relative to /api/ext/<name>. The template of the route is no literal
+// path: it matches the versioned and the legacy mount
+func stripPrefix(route *mux.Route, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var pairs []string
+		for k, v := range mux.Vars(r) {
+			pairs = append(pairs, k, v)
+		}
+
+		prefix, err := route.URLPath(pairs...)
+		if err != nil {
+			log.Errorf("Failed to strip the prefix of %s: %s", r.URL.Path, err)
+			share.HTTPError(rw, err)
+			return
+		}
+
+		http.StripPrefix(prefix.Path, h).ServeHTTP(rw, r)
+	})
+}
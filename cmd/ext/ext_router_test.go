@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package ext
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// echoPlugin a running plugin served in process: it replies the path and
+// vars of every request it reads
+func echoPlugin(t *testing.T) *Plugin {
+	reqReader, reqWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	respReader, respWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		reqWriter.Close()
+		respReader.Close()
+	})
+
+	go func() {
+		defer reqReader.Close()
+		defer respWriter.Close()
+
+		dec := json.NewDecoder(reqReader)
+		enc := json.NewEncoder(respWriter)
+
+		for {
+			req := new(Request)
+			if dec.Decode(req) != nil {
+				return
+			}
+
+			body, _ := json.Marshal(map[string]string{"path": req.Path, "text": req.Vars["text"]})
+			enc.Encode(&Response{ID: req.ID, Body: body})
+		}
+	}()
+
+	p := newPlugin("/etc/api-routerd/ext/hello.py")
+	p.running(reqWriter, &Handshake{
+		Protocol: ProtocolVersion,
+		Routes:   []Route{{Method: "GET", Path: "/sayhello/{text}"}},
+	})
+
+	go p.relay(json.NewDecoder(respReader))
+
+	return p
+}
+
+func TestPluginMounts(t *testing.T) {
+	plugins.Lock()
+	plugins.list = []*Plugin{echoPlugin(t)}
+	plugins.Unlock()
+
+	r := mux.NewRouter()
+	RegisterRouterExt(r.PathPrefix("/api{apiVersion:(?:/v1|/v2)?}").Subrouter())
+
+	for _, path := range []string{"/api/ext/hello/sayhello/world", "/api/v1/ext/hello/sayhello/world"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d: %s", path, w.Code, w.Body)
+		}
+
+		var reply map[string]string
+		err := json.NewDecoder(w.Body).Decode(&reply)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if reply["path"] != "/sayhello/world" || reply["text"] != "world" {
+			t.Errorf("GET %s reached the plugin as %v", path, reply)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package ext
+
+import (
+	"encoding/json"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+)
+
+// ProtocolVersion of the JSON messages exchanged with ext plugins.
+//
+// A plugin is an executable in the ext directory. api-routerd starts it and the
+// plugin writes a Handshake to its stdout. Requests are then written to the
+// plugin's stdin and the plugin writes one Response per Request to its stdout,
+// or over the Unix socket it names in the Handshake. Messages are JSON values,
+// one per line, and responses may come in any order.
+const ProtocolVersion = 1
+
+//Route a route declared in the handshake. Path is a mux template below /api/ext/<name>
+type Route struct {
+	Method  string `json:"method"`
+	Path    string `json:"path"`
+	Summary string `json:"summary,omitempty"`
+}
+
+//Handshake the first message of a plugin
+type Handshake struct {
+	Protocol int     `json:"protocol"`
+	Version  string  `json:"version"`
+	Routes   []Route `json:"routes"`
+
+	// Unix socket the plugin listens on. Empty to keep using stdin and stdout
+	Socket string `json:"socket,omitempty"`
+}
+
+//Request an HTTP request to one of the routes of the plugin
+type Request struct {
+	ID       uint64              `json:"id"`
+	Method   string              `json:"method"`
+	Path     string              `json:"path"`
+	Route    string              `json:"route"`
+	Vars     map[string]string   `json:"vars,omitempty"`
+	Query    map[string][]string `json:"query,omitempty"`
+	Body     json.RawMessage     `json:"body,omitempty"`
+	Identity *share.Identity     `json:"identity,omitempty"`
+}
+
+//Response the reply of the plugin to the request with the same ID. Status
+//defaults to 200, or to 500 when Error is set
+type Response struct {
+	ID     uint64          `json:"id"`
+	Status int             `json:"status,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/conf"
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	checkTimeout = 5 * time.Second
+
+	// /healthz is unauthenticated, probes within this window share one run
+	cacheTTL = 5 * time.Second
+)
+
+// overall and check status
+const (
+	StatusOK       = "ok"
+	StatusDegraded = "degraded"
+	StatusFailed   = "failed"
+)
+
+//Check the outcome of one dependency check
+type Check struct {
+	Name     string  `json:"name"`
+	Status   string  `json:"status"`
+	Required bool    `json:"required"`
+	Latency  float64 `json:"latency_ms"`
+	Error    string  `json:"error,omitempty"`
+}
+
+//Report the status of every dependency
+type Report struct {
+	Status string    `json:"status"`
+	Time   time.Time `json:"time"`
+	Checks []Check   `json:"checks"`
+}
+
+var checks = map[string]func() error{
+	"dbus":      func() error { return share.PingSystemBus("", checkTimeout) },
+	"systemd":   func() error { return share.PingSystemBus("org.freedesktop.systemd1", checkTimeout) },
+	"networkd":  func() error { return share.PingSystemBus("org.freedesktop.network1", checkTimeout) },
+	"firewalld": func() error { return share.PingSystemBus("org.fedoraproject.FirewallD1", checkTimeout) },
+	"machined":  func() error { return share.PingSystemBus("org.freedesktop.machine1", checkTimeout) },
+	"netlink":   checkNetlink,
+	"etc":       checkEtc,
+}
+
+var cache = struct {
+	sync.Mutex
+	report *Report
+}{}
+
+func checkNetlink() error {
+	_, err := netlink.LinkList()
+	return err
+}
+
+// checkEtc the config files api-routerd manages live below /etc. A read
+// only file system is reported as EROFS
+func checkEtc() error {
+	err := unix.Access("/etc", unix.W_OK)
+	if err != nil {
+		return fmt.Errorf("/etc is not writable: %s", err)
+	}
+
+	return nil
+}
+
+func run(name string, check func() error) Check {
+	c := Check{
+		Name:     name,
+		Status:   StatusOK,
+		Required: conf.HealthRequired(name),
+	}
+
+	start := time.Now()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- check()
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(checkTimeout):
+		err = fmt.Errorf("No result within %s", checkTimeout)
+	}
+
+	c.Latency = float64(time.Since(start)) / float64(time.Millisecond)
+
+	if err != nil {
+		c.Status = StatusFailed
+		c.Error = err.Error()
+	}
+
+	return c
+}
+
+//Run all checks at once. The report is failed when a required check
+//fails and degraded when only optional ones do
+func Run() *Report {
+	r := &Report{
+		Status: StatusOK,
+		Time:   time.Now().UTC(),
+		Checks: make([]Check, len(conf.HealthChecks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, name := range conf.HealthChecks {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			r.Checks[i] = run(name, checks[name])
+		}(i, name)
+	}
+	wg.Wait()
+
+	for _, c := range r.Checks {
+		if c.Status == StatusOK {
+			continue
+		}
+
+		if c.Required {
+			r.Status = StatusFailed
+		} else if r.Status == StatusOK {
+			r.Status = StatusDegraded
+		}
+	}
+
+	return r
+}
+
+// cached the last report while it is fresh
+func cached() *Report {
+	cache.Lock()
+	defer cache.Unlock()
+
+	if cache.report == nil || time.Since(cache.report.Time) > cacheTTL {
+		cache.report = Run()
+	}
+
+	return cache.report
+}
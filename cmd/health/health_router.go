@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import (
+	"net/http"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+)
+
+//Status the overall status of a /healthz reply
+type Status struct {
+	Status string `json:"status"`
+}
+
+func httpStatus(r *Report) int {
+	if r.Status == StatusFailed {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusOK
+}
+
+//Healthz only tells whether api-routerd is healthy, the details need
+// authentication
+func Healthz(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET", "HEAD":
+		report := cached()
+
+		err := share.JSONResponseStatus(Status{Status: report.Status}, httpStatus(report), rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+func routerGetHealth(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		report := Run()
+
+		err := share.JSONResponseStatus(report, httpStatus(report), rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+//RegisterRouterHealth register the unauthenticated /healthz on the root router
+func RegisterRouterHealth(router *mux.Router) {
+	share.Describe(router.HandleFunc("/healthz", Healthz),
+		share.Op("GET", "Whether api-routerd is healthy, no authentication", nil, Status{}))
+}
+
+//RegisterRouterHealthReport register the dependency report under /api
+func RegisterRouterHealthReport(router *mux.Router) {
+	share.Describe(router.HandleFunc("/health", routerGetHealth),
+		share.Op("GET", "Status, latency and error of every dependency check", nil, Report{}))
+}
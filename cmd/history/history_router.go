@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package history
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+)
+
+//Diff changes of a file between two revisions, To 0 is the file on disk
+type Diff struct {
+	Path string `json:"path"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+	Diff string `json:"diff"`
+}
+
+//Revert json request to restore a revision
+type Revert struct {
+	Path     string `json:"path"`
+	Revision int    `json:"revision"`
+}
+
+func parseRevision(r *http.Request, key string) (int, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.Atoi(v)
+	if err != nil || id <= 0 {
+		return 0, share.NewError(http.StatusBadRequest, "Invalid %s '%s'", key, v)
+	}
+
+	return id, nil
+}
+
+// diff from revision from, the latest for 0, to revision to, the file on
+// disk for 0
+func diff(r *http.Request) (*Diff, error) {
+	path := r.URL.Query().Get("path")
+
+	from, err := parseRevision(r, "from")
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := parseRevision(r, "to")
+	if err != nil {
+		return nil, err
+	}
+
+	if from == 0 {
+		revs, err := share.Revisions(path)
+		if err != nil {
+			return nil, err
+		}
+
+		from = revs[len(revs)-1].ID
+	}
+
+	_, a, err := share.RevisionContent(path, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	toName := path
+	if to == 0 {
+		b, err = ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	} else {
+		_, b, err = share.RevisionContent(path, to)
+		if err != nil {
+			return nil, err
+		}
+
+		toName = path + "@" + strconv.Itoa(to)
+	}
+
+	return &Diff{
+		Path: path,
+		From: from,
+		To:   to,
+		Diff: share.UnifiedDiff(path+"@"+strconv.Itoa(from), toName, string(a), string(b)),
+	}, nil
+}
+
+func routerGetFiles(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		files, err := share.HistoryFiles()
+		if err != nil {
+			share.HTTPError(rw, err)
+			return
+		}
+
+		if files == nil {
+			files = []string{}
+		}
+
+		err = share.JSONResponse(files, rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+func routerGetHistory(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		revs, err := share.Revisions(r.URL.Query().Get("path"))
+		if err != nil {
+			share.HTTPError(rw, err)
+			return
+		}
+
+		err = share.JSONResponse(revs, rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+func routerGetDiff(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		d, err := diff(r)
+		if err != nil {
+			share.HTTPError(rw, err)
+			return
+		}
+
+		err = share.JSONResponse(d, rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+func routerRevert(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		req := new(Revert)
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			share.HTTPError(rw, share.BadRequest(err))
+			return
+		}
+
+		rev, err := share.RevertConfigFile(r, req.Path, req.Revision)
+		if err != nil {
+			share.HTTPError(rw, err)
+			return
+		}
+
+		// with [History] Keep=0 the revert is not recorded
+		if rev == nil {
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		err = share.JSONResponse(rev, rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+//RegisterRouterHistory register with mux
+func RegisterRouterHistory(router *mux.Router) {
+	s := router.PathPrefix("/config").Subrouter().StrictSlash(false)
+
+	share.Describe(s.HandleFunc("/files", routerGetFiles),
+		share.Op("GET", "Files with a history", nil, []string{}))
+	share.Describe(s.HandleFunc("/history", routerGetHistory),
+		share.Op("GET", "Revisions of ?path=, oldest first", nil, []share.Revision{}))
+	share.Describe(s.HandleFunc("/diff", routerGetDiff),
+		share.Op("GET", "Diff of ?path= from revision ?from=, the latest by default, to ?to=, the file on disk by default", nil, Diff{}))
+	share.Describe(s.HandleFunc("/revert", routerRevert),
+		share.Op("POST", "Write a revision of a file back as a new revision", Revert{}, share.Revision{}).Previewable())
+}
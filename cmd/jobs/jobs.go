@@ -0,0 +1,284 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// jobs running at once, the others wait queued
+	maxRunning = 8
+
+	// finished jobs are kept this long or until maxJobs is reached
+	retention = time.Hour
+	maxJobs   = 1000
+)
+
+// job states
+const (
+	StateQueued  = "queued"
+	StateRunning = "running"
+	StateDone    = "done"
+	StateFailed  = "failed"
+)
+
+//Func the work of a job. It returns the outcome reported as the job result,
+//for systemd the job result such as done, failed or timeout
+type Func func() (string, error)
+
+//Job a long operation running in the background
+type Job struct {
+	ID        string     `json:"id"`
+	Operation string     `json:"operation"`
+	User      string     `json:"user,omitempty"`
+	State     string     `json:"state"`
+	Result    string     `json:"result,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Created   time.Time  `json:"created"`
+	Started   *time.Time `json:"started,omitempty"`
+	Finished  *time.Time `json:"finished,omitempty"`
+}
+
+type job struct {
+	lock sync.Mutex
+	job  Job
+	done chan struct{}
+}
+
+var store = struct {
+	sync.Mutex
+	jobs  map[string]*job
+	order []*job
+}{jobs: make(map[string]*job)}
+
+var running = make(chan struct{}, maxRunning)
+
+func (j *job) snapshot() Job {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	return j.job
+}
+
+func (j *job) finished() bool {
+	select {
+	case <-j.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// prune must be called with the store lock held
+func prune() {
+	kept := store.order[:0]
+	excess := len(store.order) - maxJobs
+
+	for _, j := range store.order {
+		if j.finished() {
+			s := j.snapshot()
+			if excess > 0 || time.Since(*s.Finished) > retention {
+				delete(store.jobs, s.ID)
+				excess--
+				continue
+			}
+		}
+
+		kept = append(kept, j)
+	}
+
+	store.order = kept
+}
+
+func (j *job) run(f Func) {
+	running <- struct{}{}
+
+	var result string
+	var err error
+
+	// a panicking job fails alone instead of taking the daemon down
+	defer func() {
+		if e := recover(); e != nil {
+			log.Errorf("Job %s '%s' panicked: %v\n%s", j.job.ID, j.job.Operation, e, debug.Stack())
+			err = fmt.Errorf("Panic: %v", e)
+		}
+
+		j.finish(result, err)
+		<-running
+	}()
+
+	started := time.Now().UTC()
+
+	j.lock.Lock()
+	j.job.State = StateRunning
+	j.job.Started = &started
+	j.lock.Unlock()
+
+	result, err = f()
+}
+
+func (j *job) finish(result string, err error) {
+	finished := time.Now().UTC()
+
+	j.lock.Lock()
+	j.job.Result = result
+	j.job.Finished = &finished
+
+	if err != nil {
+		j.job.State = StateFailed
+		j.job.Error = err.Error()
+		log.Errorf("Job %s '%s' failed: %s", j.job.ID, j.job.Operation, err)
+	} else {
+		j.job.State = StateDone
+	}
+	j.lock.Unlock()
+
+	close(j.done)
+}
+
+//Submit queues f and returns the job tracking it
+func Submit(r *http.Request, operation string, f Func) (Job, error) {
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	j := &job{
+		job: Job{
+			ID:        id,
+			Operation: operation,
+			State:     StateQueued,
+			Created:   time.Now().UTC(),
+		},
+		done: make(chan struct{}),
+	}
+
+	if identity := share.RequestIdentity(r); identity != nil {
+		j.job.User = identity.User
+	}
+
+	store.Lock()
+	prune()
+	store.jobs[id] = j
+	store.order = append(store.order, j)
+	store.Unlock()
+
+	go j.run(f)
+
+	return j.snapshot(), nil
+}
+
+//Run submits f and replies 202 Accepted with the job. Inside a transaction,
+//such as a step of a batch, it waits for the job and replies it finished,
+//with 500 when it failed, so a failure rolls the transaction back
+func Run(rw http.ResponseWriter, r *http.Request, operation string, f Func) error {
+	j, err := Submit(r, operation, f)
+	if err != nil {
+		return err
+	}
+
+	rw.Header().Set("Location", fmt.Sprintf("/api/%s/jobs/%s", share.APIVersion(r), j.ID))
+
+	if share.InTransaction(r) == nil {
+		return share.JSONResponseStatus(j, http.StatusAccepted, rw)
+	}
+
+	j, err = wait(j.ID, r.Context().Done())
+	if err != nil {
+		return err
+	}
+
+	status := http.StatusOK
+	switch j.State {
+	case StateFailed:
+		status = http.StatusInternalServerError
+	case StateQueued, StateRunning:
+		// the caller went away
+		status = http.StatusAccepted
+	}
+
+	return share.JSONResponseStatus(j, status, rw)
+}
+
+// wait the job id once it finished or cancel is closed
+func wait(id string, cancel <-chan struct{}) (Job, error) {
+	j, err := lookup(id)
+	if err != nil {
+		return Job{}, err
+	}
+
+	select {
+	case <-j.done:
+	case <-cancel:
+	}
+
+	return j.snapshot(), nil
+}
+
+func lookup(id string) (*job, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	j, ok := store.jobs[id]
+	if !ok {
+		return nil, share.NotFound(fmt.Errorf("Job '%s' not found", id))
+	}
+
+	return j, nil
+}
+
+//Get the job id, waiting at most wait for it to finish
+func Get(id string, wait time.Duration, cancel <-chan struct{}) (Job, error) {
+	j, err := lookup(id)
+	if err != nil {
+		return Job{}, err
+	}
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+
+		select {
+		case <-j.done:
+		case <-timer.C:
+		case <-cancel:
+		}
+	}
+
+	return j.snapshot(), nil
+}
+
+//List all jobs in the order they were submitted
+func List() []Job {
+	store.Lock()
+	defer store.Unlock()
+
+	list := make([]Job, 0, len(store.order))
+	for _, j := range store.order {
+		list = append(list, j.snapshot())
+	}
+
+	return list
+}
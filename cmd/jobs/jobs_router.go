@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package jobs
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	maxWait = 5 * time.Minute
+)
+
+func routerGetJobs(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		err := share.JSONResponse(List(), rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+func routerGetJob(rw http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	switch r.Method {
+	case "GET":
+		var wait time.Duration
+		var err error
+
+		if v := r.URL.Query().Get("wait"); v != "" {
+			wait, err = time.ParseDuration(v)
+			if err != nil || wait < 0 {
+				share.HTTPError(rw, share.NewError(http.StatusBadRequest, "Invalid wait '%s'", v))
+				return
+			}
+
+			if wait > maxWait {
+				wait = maxWait
+			}
+		}
+
+		j, err := Get(id, wait, r.Context().Done())
+		if err != nil {
+			share.HTTPError(rw, err)
+			return
+		}
+
+		err = share.JSONResponse(j, rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+//RegisterRouterJobs register with mux
+func RegisterRouterJobs(router *mux.Router) {
+	share.Describe(router.HandleFunc("/jobs", routerGetJobs),
+		share.Op("GET", "List the background jobs", nil, []Job{}))
+	share.Describe(router.HandleFunc("/jobs/{id}", routerGetJob),
+		share.Op("GET", "State of a job, ?wait=30s blocks until it finishes", nil, Job{}))
+}
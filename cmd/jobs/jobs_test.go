@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package jobs
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRunPanic(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/v1/service/restart", nil)
+
+	// more panics than slots, each must give its slot back
+	for i := 0; i < maxRunning+1; i++ {
+		j, err := Submit(r, "panic", func() (string, error) {
+			panic("boom")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		j, err = wait(j.ID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if j.State != StateFailed || !strings.Contains(j.Error, "boom") {
+			t.Errorf("panicking job %s '%s', want %s", j.State, j.Error, StateFailed)
+		}
+	}
+}
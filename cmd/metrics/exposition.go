@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Prometheus text exposition format
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// metric types
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writer builds a scrape. Every family starts with header and is followed by
+// its samples
+type writer struct {
+	buf bytes.Buffer
+}
+
+func (w *writer) header(name string, kind string, help string) {
+	fmt.Fprintf(&w.buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// sample writes one sample. labels are name and value pairs
+func (w *writer) sample(name string, value float64, labels ...string) {
+	w.buf.WriteString(name)
+
+	if len(labels) > 0 {
+		w.buf.WriteByte('{')
+
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+
+			fmt.Fprintf(&w.buf, `%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1]))
+		}
+
+		w.buf.WriteByte('}')
+	}
+
+	w.buf.WriteByte(' ')
+	w.buf.WriteString(formatValue(value))
+	w.buf.WriteByte('\n')
+}
+
+func (w *writer) gauge(name string, help string, value float64) {
+	w.header(name, typeGauge, help)
+	w.sample(name, value)
+}
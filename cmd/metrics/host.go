@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"sort"
+
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/host"
+	"github.com/shirou/gopsutil/load"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/net"
+)
+
+// disk read and write times are reported in milliseconds
+const msPerSecond = 1000.0
+
+func writeLoad(w *writer) error {
+	l, err := load.Avg()
+	if err != nil {
+		return err
+	}
+
+	w.gauge("node_load1", "1m load average.", l.Load1)
+	w.gauge("node_load5", "5m load average.", l.Load5)
+	w.gauge("node_load15", "15m load average.", l.Load15)
+
+	return nil
+}
+
+func writeMemory(w *writer) error {
+	m, err := mem.VirtualMemory()
+	if err != nil {
+		return err
+	}
+
+	w.gauge("node_memory_total_bytes", "Total memory in bytes.", float64(m.Total))
+	w.gauge("node_memory_available_bytes", "Memory available for new programs in bytes.", float64(m.Available))
+	w.gauge("node_memory_used_bytes", "Used memory in bytes.", float64(m.Used))
+	w.gauge("node_memory_free_bytes", "Free memory in bytes.", float64(m.Free))
+	w.gauge("node_memory_buffers_bytes", "Memory used by buffers in bytes.", float64(m.Buffers))
+	w.gauge("node_memory_cached_bytes", "Memory used by the page cache in bytes.", float64(m.Cached))
+
+	return nil
+}
+
+func writeSwap(w *writer) error {
+	s, err := mem.SwapMemory()
+	if err != nil {
+		return err
+	}
+
+	w.gauge("node_swap_total_bytes", "Total swap in bytes.", float64(s.Total))
+	w.gauge("node_swap_used_bytes", "Used swap in bytes.", float64(s.Used))
+	w.gauge("node_swap_free_bytes", "Free swap in bytes.", float64(s.Free))
+
+	return nil
+}
+
+func writeCPU(w *writer) error {
+	times, err := cpu.Times(true)
+	if err != nil {
+		return err
+	}
+
+	w.header("node_cpu_seconds_total", typeCounter, "Seconds the CPUs spent in each mode.")
+	for _, t := range times {
+		modes := []struct {
+			mode  string
+			value float64
+		}{
+			{"user", t.User},
+			{"nice", t.Nice},
+			{"system", t.System},
+			{"idle", t.Idle},
+			{"iowait", t.Iowait},
+			{"irq", t.Irq},
+			{"softirq", t.Softirq},
+			{"steal", t.Steal},
+			{"guest", t.Guest},
+			{"guest_nice", t.GuestNice},
+		}
+
+		for _, m := range modes {
+			w.sample("node_cpu_seconds_total", m.value, "cpu", t.CPU, "mode", m.mode)
+		}
+	}
+
+	return nil
+}
+
+func writeDisk(w *writer) error {
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return err
+	}
+
+	devices := make([]string, 0, len(counters))
+	for d := range counters {
+		devices = append(devices, d)
+	}
+	sort.Strings(devices)
+
+	families := []struct {
+		name  string
+		kind  string
+		help  string
+		value func(disk.IOCountersStat) float64
+	}{
+		{"node_disk_reads_completed_total", typeCounter, "Reads completed.",
+			func(c disk.IOCountersStat) float64 { return float64(c.ReadCount) }},
+		{"node_disk_writes_completed_total", typeCounter, "Writes completed.",
+			func(c disk.IOCountersStat) float64 { return float64(c.WriteCount) }},
+		{"node_disk_read_bytes_total", typeCounter, "Bytes read.",
+			func(c disk.IOCountersStat) float64 { return float64(c.ReadBytes) }},
+		{"node_disk_written_bytes_total", typeCounter, "Bytes written.",
+			func(c disk.IOCountersStat) float64 { return float64(c.WriteBytes) }},
+		{"node_disk_read_time_seconds_total", typeCounter, "Seconds spent reading.",
+			func(c disk.IOCountersStat) float64 { return float64(c.ReadTime) / msPerSecond }},
+		{"node_disk_write_time_seconds_total", typeCounter, "Seconds spent writing.",
+			func(c disk.IOCountersStat) float64 { return float64(c.WriteTime) / msPerSecond }},
+		{"node_disk_io_time_seconds_total", typeCounter, "Seconds spent doing I/O.",
+			func(c disk.IOCountersStat) float64 { return float64(c.IoTime) / msPerSecond }},
+		{"node_disk_io_now", typeGauge, "I/Os currently in progress.",
+			func(c disk.IOCountersStat) float64 { return float64(c.IopsInProgress) }},
+	}
+
+	for _, f := range families {
+		w.header(f.name, f.kind, f.help)
+		for _, d := range devices {
+			w.sample(f.name, f.value(counters[d]), "device", d)
+		}
+	}
+
+	return nil
+}
+
+func writeNetwork(w *writer) error {
+	counters, err := net.IOCounters(true)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(counters, func(i, j int) bool { return counters[i].Name < counters[j].Name })
+
+	families := []struct {
+		name  string
+		help  string
+		value func(net.IOCountersStat) uint64
+	}{
+		{"node_network_receive_bytes_total", "Bytes received.",
+			func(c net.IOCountersStat) uint64 { return c.BytesRecv }},
+		{"node_network_transmit_bytes_total", "Bytes sent.",
+			func(c net.IOCountersStat) uint64 { return c.BytesSent }},
+		{"node_network_receive_packets_total", "Packets received.",
+			func(c net.IOCountersStat) uint64 { return c.PacketsRecv }},
+		{"node_network_transmit_packets_total", "Packets sent.",
+			func(c net.IOCountersStat) uint64 { return c.PacketsSent }},
+		{"node_network_receive_errs_total", "Receive errors.",
+			func(c net.IOCountersStat) uint64 { return c.Errin }},
+		{"node_network_transmit_errs_total", "Transmit errors.",
+			func(c net.IOCountersStat) uint64 { return c.Errout }},
+		{"node_network_receive_drop_total", "Received packets dropped.",
+			func(c net.IOCountersStat) uint64 { return c.Dropin }},
+		{"node_network_transmit_drop_total", "Sent packets dropped.",
+			func(c net.IOCountersStat) uint64 { return c.Dropout }},
+	}
+
+	for _, f := range families {
+		w.header(f.name, typeCounter, f.help)
+		for _, c := range counters {
+			w.sample(f.name, float64(f.value(c)), "interface", c.Name)
+		}
+	}
+
+	return nil
+}
+
+func writeTemperatures(w *writer) error {
+	sensors, err := host.SensorsTemperatures()
+	if err != nil && len(sensors) == 0 {
+		return err
+	}
+
+	w.header("node_temperature_celsius", typeGauge, "Temperature reported by the hardware sensors.")
+	for _, s := range sensors {
+		w.sample("node_temperature_celsius", s.Temperature, "sensor", s.SensorKey)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// request latency buckets in seconds
+var buckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type routeKey struct {
+	route  string
+	method string
+}
+
+type routeStats struct {
+	codes   map[int]uint64
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+var requests = struct {
+	sync.Mutex
+	routes map[routeKey]*routeStats
+}{routes: make(map[routeKey]*routeStats)}
+
+// methodLabel the method as a label. Requests are counted before they are
+// authenticated, so any method outside of the standard ones is "other"
+func methodLabel(method string) string {
+	switch method {
+	case "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "CONNECT", "TRACE":
+		return method
+	}
+
+	return "other"
+}
+
+//ObserveRequest counts a request served by route, the mux path template
+func ObserveRequest(route string, method string, code int, duration time.Duration) {
+	requests.Lock()
+	defer requests.Unlock()
+
+	k := routeKey{route: route, method: methodLabel(method)}
+
+	s, ok := requests.routes[k]
+	if !ok {
+		s = &routeStats{
+			codes:   make(map[int]uint64),
+			buckets: make([]uint64, len(buckets)),
+		}
+		requests.routes[k] = s
+	}
+
+	seconds := duration.Seconds()
+
+	s.codes[code]++
+	s.sum += seconds
+	s.count++
+
+	for i, b := range buckets {
+		if seconds <= b {
+			s.buckets[i]++
+		}
+	}
+}
+
+//Requests how many requests were served and how many of them failed
+//with a server error
+func Requests() (uint64, uint64) {
+	requests.Lock()
+	defer requests.Unlock()
+
+	var total, failed uint64
+	for _, s := range requests.routes {
+		for code, n := range s.codes {
+			total += n
+
+			if code >= 500 {
+				failed += n
+			}
+		}
+	}
+
+	return total, failed
+}
+
+func writeRequests(w *writer) {
+	requests.Lock()
+	defer requests.Unlock()
+
+	keys := make([]routeKey, 0, len(requests.routes))
+	for k := range requests.routes {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route == keys[j].route {
+			return keys[i].method < keys[j].method
+		}
+
+		return keys[i].route < keys[j].route
+	})
+
+	w.header("apirouterd_http_requests_total", typeCounter, "Requests served per route, method and status code.")
+	for _, k := range keys {
+		s := requests.routes[k]
+
+		codes := make([]int, 0, len(s.codes))
+		for c := range s.codes {
+			codes = append(codes, c)
+		}
+		sort.Ints(codes)
+
+		for _, c := range codes {
+			w.sample("apirouterd_http_requests_total", float64(s.codes[c]),
+				"route", k.route, "method", k.method, "code", strconv.Itoa(c))
+		}
+	}
+
+	w.header("apirouterd_http_request_duration_seconds", typeHistogram, "Latency of the requests per route and method.")
+	for _, k := range keys {
+		s := requests.routes[k]
+
+		for i, b := range buckets {
+			w.sample("apirouterd_http_request_duration_seconds_bucket", float64(s.buckets[i]),
+				"route", k.route, "method", k.method, "le", formatValue(b))
+		}
+
+		w.sample("apirouterd_http_request_duration_seconds_bucket", float64(s.count),
+			"route", k.route, "method", k.method, "le", "+Inf")
+		w.sample("apirouterd_http_request_duration_seconds_sum", s.sum, "route", k.route, "method", k.method)
+		w.sample("apirouterd_http_request_duration_seconds_count", float64(s.count), "route", k.route, "method", k.method)
+	}
+}
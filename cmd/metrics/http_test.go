@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObserveRequestMethods(t *testing.T) {
+	ObserveRequest("/api/v1/network/link/get", "GET", 200, time.Millisecond)
+	ObserveRequest("/api/v1/network/link/get", "FOO", 405, time.Millisecond)
+	ObserveRequest("/api/v1/network/link/get", "BAR", 405, time.Millisecond)
+
+	requests.Lock()
+	defer requests.Unlock()
+
+	for k := range requests.routes {
+		if k.method != "GET" && k.method != "other" {
+			t.Errorf("request counted with method label '%s'", k.method)
+		}
+	}
+
+	s, ok := requests.routes[routeKey{route: "/api/v1/network/link/get", method: "other"}]
+	if !ok || s.count != 2 {
+		t.Errorf("unknown methods not counted as 'other': %v", s)
+	}
+}
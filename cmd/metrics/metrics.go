@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/RestGW/api-routerd/cmd/systemd"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type collector struct {
+	name  string
+	write func(w *writer) error
+}
+
+// a failing collector is reported through apirouterd_collector_success
+// and does not fail the whole scrape
+var collectors = []collector{
+	{"load", writeLoad},
+	{"memory", writeMemory},
+	{"swap", writeSwap},
+	{"cpu", writeCPU},
+	{"disk", writeDisk},
+	{"network", writeNetwork},
+	{"temperature", writeTemperatures},
+	{"systemd", writeSystemd},
+}
+
+func writeSystemd(w *writer) error {
+	n, err := systemd.FailedUnits()
+	if err != nil {
+		return err
+	}
+
+	w.gauge("node_systemd_failed_units", "Number of systemd units in failed state.", float64(n))
+
+	return nil
+}
+
+func writeDaemon(w *writer) {
+	w.gauge("apirouterd_goroutines", "Number of goroutines of api-routerd.", float64(runtime.NumGoroutine()))
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	w.gauge("apirouterd_memory_alloc_bytes", "Heap bytes allocated by api-routerd.", float64(m.Alloc))
+	w.gauge("apirouterd_memory_sys_bytes", "Bytes obtained from the system by api-routerd.", float64(m.Sys))
+
+	writeRequests(w)
+}
+
+//Write a full scrape of the host and daemon metrics
+func Write(rw http.ResponseWriter) error {
+	w := new(writer)
+
+	success := make([]float64, len(collectors))
+	for i, c := range collectors {
+		// samples of a failed collector are dropped
+		mark := w.buf.Len()
+
+		err := c.write(w)
+		if err != nil {
+			log.Debugf("Failed to collect %s metrics: %s", c.name, err)
+			w.buf.Truncate(mark)
+			continue
+		}
+
+		success[i] = 1
+	}
+
+	w.header("apirouterd_collector_success", typeGauge, "Whether a collector succeeded.")
+	for i, c := range collectors {
+		w.sample("apirouterd_collector_success", success[i], "collector", c.name)
+	}
+
+	writeDaemon(w)
+
+	rw.Header().Set("Content-Type", contentType)
+	rw.WriteHeader(http.StatusOK)
+
+	_, err := w.buf.WriteTo(rw)
+	return err
+}
@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+)
+
+func routerGetMetrics(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		err := Write(rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+//RegisterRouterMetrics register metrics endpoint
+func RegisterRouterMetrics(router *mux.Router) {
+	share.Describe(router.HandleFunc("/metrics", routerGetMetrics),
+		share.Op("GET", "Host and daemon metrics in Prometheus text format", nil, nil))
+}
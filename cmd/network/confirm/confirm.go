@@ -0,0 +1,262 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package confirm
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// confirm_timeout bounds
+	minTimeout = 10 * time.Second
+	maxTimeout = time.Hour
+
+	// finished commits are kept this long
+	retention = time.Hour
+)
+
+// commit states
+const (
+	StatePending        = "pending"
+	StateConfirmed      = "confirmed"
+	StateRolledBack     = "rolled_back"
+	StateRollbackFailed = "rollback_failed"
+)
+
+//Commit network changes that are rolled back unless confirmed before Deadline.
+//Changes made while a commit is pending join it. Compensations are what a
+//rollback runs, last first
+type Commit struct {
+	ID            string     `json:"id"`
+	User          string     `json:"user,omitempty"`
+	State         string     `json:"state"`
+	Operations    []string   `json:"operations"`
+	Compensations []string   `json:"compensations,omitempty"`
+	Created       time.Time  `json:"created"`
+	Deadline      time.Time  `json:"deadline"`
+	Finished      *time.Time `json:"finished,omitempty"`
+	Error         string     `json:"error,omitempty"`
+}
+
+// change one operation of a commit and how to undo it
+type change struct {
+	operation string
+	tx        *share.Transaction
+}
+
+type commit struct {
+	commit  Commit
+	changes []change
+	timer   *time.Timer
+}
+
+var store = struct {
+	sync.Mutex
+	commits map[string]*commit
+	pending *commit
+}{commits: make(map[string]*commit)}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// prune must be called with the store lock held
+func prune() {
+	for id, c := range store.commits {
+		if c.commit.Finished != nil && time.Since(*c.commit.Finished) > retention {
+			delete(store.commits, id)
+		}
+	}
+}
+
+//ParseTimeout the ?confirm_timeout= of a request, 0 when absent
+func ParseTimeout(r *http.Request) (time.Duration, error) {
+	v := r.URL.Query().Get("confirm_timeout")
+	if v == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d < minTimeout || d > maxTimeout {
+		return 0, share.NewError(http.StatusBadRequest, "Invalid confirm_timeout '%s', must be between %s and %s", v, minTimeout, maxTimeout)
+	}
+
+	return d, nil
+}
+
+//Begin adds a change applied with the compensations in tx to the pending
+//commit, or starts a commit rolled back after timeout. Joining a commit
+//restarts its timer
+func Begin(user string, operation string, tx *share.Transaction, timeout time.Duration) (Commit, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	prune()
+
+	p := store.pending
+	if p == nil {
+		id, err := newID()
+		if err != nil {
+			return Commit{}, err
+		}
+
+		p = &commit{
+			commit: Commit{
+				ID:      id,
+				User:    user,
+				State:   StatePending,
+				Created: time.Now().UTC(),
+			},
+		}
+
+		p.timer = time.AfterFunc(timeout, func() { expire(id) })
+
+		store.commits[id] = p
+		store.pending = p
+	} else {
+		p.timer.Reset(timeout)
+	}
+
+	p.changes = append(p.changes, change{operation: operation, tx: tx})
+	p.commit.Operations = append(p.commit.Operations, operation)
+	p.commit.Compensations = append(p.commit.Compensations, tx.Compensations()...)
+	p.commit.Deadline = time.Now().UTC().Add(timeout)
+
+	return p.commit, nil
+}
+
+func expire(id string) {
+	store.Lock()
+	defer store.Unlock()
+
+	c, ok := store.commits[id]
+	if !ok || c.commit.State != StatePending {
+		// confirmed or rolled back meanwhile
+		return
+	}
+
+	rollback(c)
+
+	if c.commit.Error != "" {
+		log.Errorf("Network commit %s was not confirmed and failed to roll back: %s", id, c.commit.Error)
+		return
+	}
+
+	log.Warnf("Network commit %s was not confirmed and is rolled back", id)
+}
+
+// rollback undoes the changes of the commit, last first. A change that
+// registered no compensation can not be undone and fails the rollback.
+// Must be called with the store lock held
+func rollback(c *commit) {
+	var errs []string
+
+	for i := len(c.changes) - 1; i >= 0; i-- {
+		ch := c.changes[i]
+
+		if len(ch.tx.Compensations()) == 0 {
+			errs = append(errs, ch.operation+": not reversible")
+			continue
+		}
+
+		err := ch.tx.Rollback()
+		if err != nil {
+			errs = append(errs, ch.operation+": "+err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		c.commit.Error = strings.Join(errs, "; ")
+		finish(c, StateRollbackFailed)
+	} else {
+		finish(c, StateRolledBack)
+	}
+}
+
+// finish must be called with the store lock held
+func finish(c *commit, state string) {
+	finished := time.Now().UTC()
+
+	c.timer.Stop()
+	c.commit.State = state
+	c.commit.Finished = &finished
+
+	if store.pending == c {
+		store.pending = nil
+	}
+}
+
+func lookup(id string) (*commit, error) {
+	c, ok := store.commits[id]
+	if !ok {
+		return nil, share.NewError(http.StatusNotFound, "Commit %s not found", id)
+	}
+
+	if c.commit.State != StatePending {
+		return nil, share.Conflict(fmt.Errorf("Commit %s is %s", id, c.commit.State))
+	}
+
+	return c, nil
+}
+
+//Confirm keeps the changes of a pending commit
+func Confirm(id string) (Commit, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	c, err := lookup(id)
+	if err != nil {
+		return Commit{}, err
+	}
+
+	finish(c, StateConfirmed)
+
+	return c.commit, nil
+}
+
+//Rollback undoes the changes of a pending commit
+func Rollback(id string, user string) (Commit, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	c, err := lookup(id)
+	if err != nil {
+		return Commit{}, err
+	}
+
+	log.Infof("User %s rolls back network commit %s", user, id)
+
+	rollback(c)
+
+	return c.commit, nil
+}
+
+//Get a commit
+func Get(id string) (Commit, error) {
+	store.Lock()
+	defer store.Unlock()
+
+	c, ok := store.commits[id]
+	if !ok {
+		return Commit{}, share.NewError(http.StatusNotFound, "Commit %s not found", id)
+	}
+
+	return c.commit, nil
+}
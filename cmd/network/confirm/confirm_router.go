@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package confirm
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func requestUser(r *http.Request) string {
+	if id := share.RequestIdentity(r); id != nil {
+		return id.User
+	}
+
+	return ""
+}
+
+//Middleware applies changes made with ?confirm_timeout= in a transaction,
+//adds them to a commit and replies 202 Accepted with it. A failed change is
+//rolled back at once and joins no commit
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout, err := ParseTimeout(r)
+		if err != nil {
+			share.HTTPError(w, err)
+			return
+		}
+
+		// a dry run changes nothing to confirm
+		if timeout == 0 || share.IsDryRun(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		op := share.RequestOperation(r)
+		if op == nil || !op.Confirm {
+			share.HTTPError(w, share.NewError(http.StatusBadRequest, "confirm_timeout not supported by %s %s", r.Method, r.URL.Path))
+			return
+		}
+
+		operation := r.Method + " " + r.URL.Path
+		tx := &share.Transaction{}
+
+		b := share.NewResponseBuffer()
+		next.ServeHTTP(b, share.WithTransaction(r, tx))
+
+		if b.Failed() {
+			err = tx.Rollback()
+			if err != nil {
+				log.Errorf("Failed to roll back failed change %s: %s", operation, err)
+			}
+
+			b.Replay(w)
+			return
+		}
+
+		c, err := Begin(requestUser(r), operation, tx, timeout)
+		if err != nil {
+			// nothing would roll the change back
+			if rerr := tx.Rollback(); rerr != nil {
+				log.Errorf("Failed to roll back change %s: %s", operation, rerr)
+			}
+
+			share.HTTPError(w, err)
+			return
+		}
+
+		w.Header().Set("Location", fmt.Sprintf("/api/%s/network/confirm/%s", share.APIVersion(r), c.ID))
+		share.JSONResponseStatus(c, http.StatusAccepted, w)
+	})
+}
+
+func routerConfirm(rw http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var c Commit
+	var err error
+
+	switch r.Method {
+	case "GET":
+		c, err = Get(id)
+		break
+	case "POST":
+		c, err = Confirm(id)
+		break
+	case "DELETE":
+		c, err = Rollback(id, requestUser(r))
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+		return
+	}
+
+	if err != nil {
+		share.HTTPError(rw, err)
+		return
+	}
+
+	err = share.JSONResponse(c, rw)
+	if err != nil {
+		share.HTTPError(rw, err)
+	}
+}
+
+//RegisterRouterConfirm register with mux
+func RegisterRouterConfirm(router *mux.Router) {
+	share.Describe(router.HandleFunc("/confirm/{id}", routerConfirm),
+		share.Op("GET", "State of a network commit", nil, Commit{}),
+		share.Op("POST", "Confirm a network commit so it is not rolled back", nil, Commit{}),
+		share.Op("DELETE", "Roll back a network commit now", nil, Commit{}))
+}
@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package confirm
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+)
+
+// testRouter a confirmable route registering the compensations named in
+// ?undo= on undone, and failing with ?fail=
+func testRouter(undone *[]string) *mux.Router {
+	r := mux.NewRouter()
+	r.Use(Middleware)
+
+	share.Describe(r.HandleFunc("/network/change", func(rw http.ResponseWriter, r *http.Request) {
+		for _, u := range r.URL.Query()["undo"] {
+			u := u
+			share.OnRollback(r, u, func() error {
+				*undone = append(*undone, u)
+				return nil
+			})
+		}
+
+		if r.URL.Query().Get("fail") != "" {
+			share.HTTPError(rw, fmt.Errorf("Failed"))
+		}
+	}), share.Op("POST", "A change", nil, nil).Confirmable())
+
+	return r
+}
+
+func postChange(t *testing.T, r *mux.Router, query string) (*httptest.ResponseRecorder, Commit) {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/network/change?confirm_timeout=60s&"+query, nil))
+
+	var c Commit
+	if w.Code == http.StatusAccepted {
+		err := json.NewDecoder(w.Body).Decode(&c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return w, c
+}
+
+func TestRollbackUndoesOwnChanges(t *testing.T) {
+	var undone []string
+	r := testRouter(&undone)
+
+	w, c := postChange(t, r, "undo=a1&undo=a2")
+	if w.Code != http.StatusAccepted || c.State != StatePending {
+		t.Fatalf("change = %d %+v", w.Code, c)
+	}
+
+	// a second change joins the pending commit
+	_, c2 := postChange(t, r, "undo=b1")
+	if c2.ID != c.ID || len(c2.Operations) != 2 || len(c2.Compensations) != 3 {
+		t.Fatalf("second change not joined: %+v", c2)
+	}
+
+	c, err := Rollback(c.ID, "max")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.State != StateRolledBack {
+		t.Errorf("commit %s: %s", c.State, c.Error)
+	}
+
+	if strings.Join(undone, ",") != "b1,a2,a1" {
+		t.Errorf("undone in order %v, want b1,a2,a1", undone)
+	}
+}
+
+func TestFailedChangeRolledBackAtOnce(t *testing.T) {
+	var undone []string
+	r := testRouter(&undone)
+
+	w, _ := postChange(t, r, "undo=a1&fail=1")
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("failed change = %d", w.Code)
+	}
+
+	if strings.Join(undone, ",") != "a1" {
+		t.Errorf("undone %v, want a1", undone)
+	}
+
+	store.Lock()
+	pending := store.pending
+	store.Unlock()
+
+	if pending != nil {
+		t.Errorf("failed change left commit %s pending", pending.commit.ID)
+	}
+}
+
+func TestRollbackNotReversible(t *testing.T) {
+	var undone []string
+	r := testRouter(&undone)
+
+	_, c := postChange(t, r, "undo=a1")
+	_, c = postChange(t, r, "")
+
+	c, err := Rollback(c.ID, "max")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.State != StateRollbackFailed || !strings.Contains(c.Error, "not reversible") {
+		t.Errorf("commit %s: '%s', want %s", c.State, c.Error, StateRollbackFailed)
+	}
+
+	// the reversible change is undone all the same
+	if strings.Join(undone, ",") != "a1" {
+		t.Errorf("undone %v, want a1", undone)
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	var undone []string
+	r := testRouter(&undone)
+
+	_, c := postChange(t, r, "undo=a1")
+
+	c, err := Confirm(c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.State != StateConfirmed || len(undone) != 0 {
+		t.Errorf("confirmed commit %s undid %v", c.State, undone)
+	}
+
+	_, err = Rollback(c.ID, "max")
+	if err == nil {
+		t.Error("confirmed commit rolled back")
+	}
+}
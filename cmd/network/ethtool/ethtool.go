@@ -26,7 +26,7 @@ func (r *Ethtool) GetEthTool(rw http.ResponseWriter) error {
 	link := share.LinkExists(r.Link)
 	if !link {
 		log.Errorf("Failed to get link: %s", r.Link)
-		return errors.New("Link not found")
+		return share.NotFound(errors.New("Link not found"))
 	}
 
 	e, err := ethtool.NewEthtool()
@@ -175,7 +175,7 @@ func (r *Ethtool) SetEthTool(rw http.ResponseWriter) error {
 	link := share.LinkExists(r.Link)
 	if !link {
 		log.Errorf("Failed to get link: %s", r.Link)
-		return errors.New("Link not found")
+		return share.NotFound(errors.New("Link not found"))
 	}
 
 	e, err := ethtool.NewEthtool()
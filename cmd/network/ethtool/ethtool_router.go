@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/RestGW/api-routerd/cmd/share"
+
 	"github.com/gorilla/mux"
 )
 
@@ -23,7 +25,7 @@ func routerConfigureEthtool(rw http.ResponseWriter, r *http.Request) {
 
 		err := ethtool.GetEthTool(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 
@@ -32,7 +34,7 @@ func routerConfigureEthtool(rw http.ResponseWriter, r *http.Request) {
 		e := new(Ethtool)
 		err := json.NewDecoder(r.Body).Decode(&e)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
@@ -41,14 +43,18 @@ func routerConfigureEthtool(rw http.ResponseWriter, r *http.Request) {
 
 		err = ethtool.SetEthTool(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
 //RegisterRouterEthtool register with mux
 func RegisterRouterEthtool(n *mux.Router) {
 	e := n.PathPrefix("/ethtool").Subrouter().StrictSlash(false)
-	e.HandleFunc("/{link}/{command}", routerConfigureEthtool)
+	share.Describe(e.HandleFunc("/{link}/{command}", routerConfigureEthtool),
+		share.Op("GET", "Run get-link-stat, get-link-features, get-link-bus, get-link-driver-name, get-link-driver-info, get-link-permaddr, get-link-eeprom, get-link-msglvl or get-link-mapped", nil, map[string]interface{}{}),
+		share.Op("POST", "Run set-link-feature", Ethtool{}, nil))
 }
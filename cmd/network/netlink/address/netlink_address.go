@@ -32,7 +32,7 @@ func DecodeJSONRequest(r *http.Request) (Address, error) {
 }
 
 //Add Add a new address to interface
-func (a *Address) Add() error {
+func (a *Address) Add(r *http.Request) error {
 	link, err := netlink.LinkByName(a.Link)
 	if err != nil {
 		return err
@@ -48,11 +48,15 @@ func (a *Address) Add() error {
 		return err
 	}
 
+	share.OnRollback(r, "delete address "+a.Address+" from link "+a.Link, func() error {
+		return netlink.AddrDel(link, addr)
+	})
+
 	return nil
 }
 
 //Del remove a address from interface
-func (a *Address) Del() error {
+func (a *Address) Del(r *http.Request) error {
 	link, err := netlink.LinkByName(a.Link)
 	if err != nil {
 		return err
@@ -68,6 +72,10 @@ func (a *Address) Del() error {
 		return err
 	}
 
+	share.OnRollback(r, "add address "+a.Address+" to link "+a.Link, func() error {
+		return netlink.AddrAdd(link, addr)
+	})
+
 	return nil
 }
 
@@ -0,0 +1,526 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package events
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// updates buffered per kind between the netlink socket and the client
+	updatesBuffer = 64
+)
+
+// event kinds
+const (
+	KindLink     = "link"
+	KindAddress  = "address"
+	KindRoute    = "route"
+	KindNeighbor = "neighbor"
+)
+
+// event types
+const (
+	LinkAdded       = "link_added"
+	LinkRemoved     = "link_removed"
+	LinkUp          = "link_up"
+	LinkDown        = "link_down"
+	CarrierUp       = "carrier_up"
+	CarrierDown     = "carrier_down"
+	LinkChanged     = "link_changed"
+	AddressAdded    = "address_added"
+	AddressRemoved  = "address_removed"
+	RouteAdded      = "route_added"
+	RouteRemoved    = "route_removed"
+	NeighborChanged = "neighbor_changed"
+	NeighborRemoved = "neighbor_removed"
+)
+
+//Event a change of a link, address, route or neighbor
+type Event struct {
+	Type  string `json:"type"`
+	Kind  string `json:"kind"`
+	Link  string `json:"link,omitempty"`
+	Index int    `json:"index,omitempty"`
+
+	// link
+	OperState string `json:"oper_state,omitempty"`
+	MTU       int    `json:"mtu,omitempty"`
+	MAC       string `json:"mac,omitempty"`
+
+	// address
+	Address string `json:"address,omitempty"`
+
+	// route
+	Destination string `json:"destination,omitempty"`
+	Gateway     string `json:"gateway,omitempty"`
+	Table       int    `json:"table,omitempty"`
+	Protocol    int    `json:"protocol,omitempty"`
+
+	// neighbor
+	IP    string `json:"ip,omitempty"`
+	State string `json:"state,omitempty"`
+
+	Time time.Time `json:"time"`
+}
+
+//Filter which events a client wants, everything when empty
+type Filter struct {
+	Links []string
+	Types []string
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// types match either the event type or its kind, so type=link is every
+// link event
+func (f *Filter) matches(e *Event) bool {
+	if len(f.Links) > 0 && !contains(f.Links, e.Link) {
+		return false
+	}
+
+	if len(f.Types) > 0 && !contains(f.Types, e.Type) && !contains(f.Types, e.Kind) {
+		return false
+	}
+
+	return true
+}
+
+var knownTypes = []string{
+	KindLink, KindAddress, KindRoute, KindNeighbor,
+	LinkAdded, LinkRemoved, LinkUp, LinkDown, CarrierUp, CarrierDown, LinkChanged,
+	AddressAdded, AddressRemoved, RouteAdded, RouteRemoved, NeighborChanged, NeighborRemoved,
+}
+
+//ParseFilter reads the link and type query parameters
+func ParseFilter(r *http.Request) (*Filter, error) {
+	q := r.URL.Query()
+
+	f := &Filter{
+		Links: q["link"],
+		Types: q["type"],
+	}
+
+	for _, t := range f.Types {
+		if !contains(knownTypes, t) {
+			return nil, share.NewError(http.StatusBadRequest, "Unknown event type '%s'", t)
+		}
+	}
+
+	return f, nil
+}
+
+// linkState what is compared between two link updates
+type linkState struct {
+	name      string
+	up        bool
+	carrier   bool
+	operState string
+	mtu       int
+	mac       string
+}
+
+func newLinkState(l netlink.Link, flags uint32) linkState {
+	a := l.Attrs()
+
+	return linkState{
+		name:      a.Name,
+		up:        a.Flags&net.FlagUp != 0,
+		carrier:   flags&unix.IFF_LOWER_UP != 0,
+		operState: a.OperState.String(),
+		mtu:       a.MTU,
+		mac:       a.HardwareAddr.String(),
+	}
+}
+
+// watcher turns the updates of one client into events. Link names are kept
+// by index so addresses, routes and neighbors can be reported by name
+type watcher struct {
+	links map[int]linkState
+}
+
+func newWatcher() (*watcher, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &watcher{links: make(map[int]linkState, len(links))}
+	for _, l := range links {
+		w.links[l.Attrs().Index] = newLinkState(l, uint32(l.Attrs().RawFlags))
+	}
+
+	return w, nil
+}
+
+func (w *watcher) linkName(index int) string {
+	if s, ok := w.links[index]; ok {
+		return s.name
+	}
+
+	l, err := netlink.LinkByIndex(index)
+	if err != nil {
+		return ""
+	}
+
+	return l.Attrs().Name
+}
+
+func (w *watcher) linkEvents(u netlink.LinkUpdate) []*Event {
+	index := int(u.Index)
+	cur := newLinkState(u.Link, u.Flags)
+	old, known := w.links[index]
+
+	event := func(t string) *Event {
+		return &Event{
+			Type:      t,
+			Kind:      KindLink,
+			Link:      cur.name,
+			Index:     index,
+			OperState: cur.operState,
+			MTU:       cur.mtu,
+			MAC:       cur.mac,
+			Time:      time.Now().UTC(),
+		}
+	}
+
+	if u.Header.Type == unix.RTM_DELLINK {
+		delete(w.links, index)
+		return []*Event{event(LinkRemoved)}
+	}
+
+	w.links[index] = cur
+
+	if !known {
+		return []*Event{event(LinkAdded)}
+	}
+
+	var events []*Event
+
+	if old.up != cur.up {
+		if cur.up {
+			events = append(events, event(LinkUp))
+		} else {
+			events = append(events, event(LinkDown))
+		}
+	}
+
+	if old.carrier != cur.carrier {
+		if cur.carrier {
+			events = append(events, event(CarrierUp))
+		} else {
+			events = append(events, event(CarrierDown))
+		}
+	}
+
+	if old.name != cur.name || old.mtu != cur.mtu || old.mac != cur.mac ||
+		(len(events) == 0 && old.operState != cur.operState) {
+		events = append(events, event(LinkChanged))
+	}
+
+	return events
+}
+
+func (w *watcher) addressEvent(u netlink.AddrUpdate) *Event {
+	e := &Event{
+		Type:    AddressAdded,
+		Kind:    KindAddress,
+		Link:    w.linkName(u.LinkIndex),
+		Index:   u.LinkIndex,
+		Address: u.LinkAddress.String(),
+		Time:    time.Now().UTC(),
+	}
+
+	if !u.NewAddr {
+		e.Type = AddressRemoved
+	}
+
+	return e
+}
+
+func (w *watcher) routeEvent(u netlink.RouteUpdate) *Event {
+	e := &Event{
+		Type:        RouteAdded,
+		Kind:        KindRoute,
+		Index:       u.LinkIndex,
+		Destination: "default",
+		Table:       u.Table,
+		Protocol:    u.Protocol,
+		Time:        time.Now().UTC(),
+	}
+
+	if u.LinkIndex > 0 {
+		e.Link = w.linkName(u.LinkIndex)
+	}
+
+	if u.Dst != nil {
+		e.Destination = u.Dst.String()
+	}
+
+	if u.Gw != nil {
+		e.Gateway = u.Gw.String()
+	}
+
+	if u.Type == unix.RTM_DELROUTE {
+		e.Type = RouteRemoved
+	}
+
+	return e
+}
+
+var neighStates = []struct {
+	state int
+	name  string
+}{
+	{netlink.NUD_INCOMPLETE, "incomplete"},
+	{netlink.NUD_REACHABLE, "reachable"},
+	{netlink.NUD_STALE, "stale"},
+	{netlink.NUD_DELAY, "delay"},
+	{netlink.NUD_PROBE, "probe"},
+	{netlink.NUD_FAILED, "failed"},
+	{netlink.NUD_NOARP, "noarp"},
+	{netlink.NUD_PERMANENT, "permanent"},
+}
+
+func neighState(state int) string {
+	for _, s := range neighStates {
+		if state&s.state != 0 {
+			return s.name
+		}
+	}
+
+	return "none"
+}
+
+func (w *watcher) neighborEvent(u neighUpdate) *Event {
+	e := &Event{
+		Type:  NeighborChanged,
+		Kind:  KindNeighbor,
+		Link:  w.linkName(u.LinkIndex),
+		Index: u.LinkIndex,
+		IP:    u.IP.String(),
+		MAC:   u.HardwareAddr.String(),
+		State: neighState(u.State),
+		Time:  time.Now().UTC(),
+	}
+
+	if u.Type == unix.RTM_DELNEIGH {
+		e.Type = NeighborRemoved
+	}
+
+	return e
+}
+
+// neighUpdate the vendored netlink has no NeighSubscribe
+type neighUpdate struct {
+	Type uint16
+	netlink.Neigh
+}
+
+func neighSubscribe(ch chan<- neighUpdate, done <-chan struct{}) error {
+	s, err := nl.Subscribe(unix.NETLINK_ROUTE, unix.RTNLGRP_NEIGH)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-done
+		s.Close()
+	}()
+
+	go func() {
+		defer close(ch)
+
+		for {
+			msgs, err := s.Receive()
+			if err != nil {
+				return
+			}
+
+			for _, m := range msgs {
+				if m.Header.Type != unix.RTM_NEWNEIGH && m.Header.Type != unix.RTM_DELNEIGH {
+					continue
+				}
+
+				n, err := netlink.NeighDeserialize(m.Data)
+				if err != nil {
+					continue
+				}
+
+				ch <- neighUpdate{Type: m.Header.Type, Neigh: *n}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// subscription the netlink sockets of one client
+type subscription struct {
+	done   chan struct{}
+	links  chan netlink.LinkUpdate
+	addrs  chan netlink.AddrUpdate
+	routes chan netlink.RouteUpdate
+	neighs chan neighUpdate
+}
+
+func subscribe() (*subscription, error) {
+	s := &subscription{done: make(chan struct{})}
+
+	links := make(chan netlink.LinkUpdate, updatesBuffer)
+	err := netlink.LinkSubscribe(links, s.done)
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	s.links = links
+
+	addrs := make(chan netlink.AddrUpdate, updatesBuffer)
+	err = netlink.AddrSubscribe(addrs, s.done)
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	s.addrs = addrs
+
+	routes := make(chan netlink.RouteUpdate, updatesBuffer)
+	err = netlink.RouteSubscribe(routes, s.done)
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	s.routes = routes
+
+	neighs := make(chan neighUpdate, updatesBuffer)
+	err = neighSubscribe(neighs, s.done)
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	s.neighs = neighs
+
+	return s, nil
+}
+
+// close stops the sockets. The readers only return once their channel is
+// drained, so whatever is still queued is read and thrown away. Channels
+// never subscribed are nil and block forever in the select
+func (s *subscription) close() {
+	close(s.done)
+
+	go func() {
+		for s.links != nil || s.addrs != nil || s.routes != nil || s.neighs != nil {
+			select {
+			case _, ok := <-s.links:
+				if !ok {
+					s.links = nil
+				}
+			case _, ok := <-s.addrs:
+				if !ok {
+					s.addrs = nil
+				}
+			case _, ok := <-s.routes:
+				if !ok {
+					s.routes = nil
+				}
+			case _, ok := <-s.neighs:
+				if !ok {
+					s.neighs = nil
+				}
+			}
+		}
+	}()
+}
+
+//Stream pushes the events matching the filter until the client goes away
+func Stream(w http.ResponseWriter, r *http.Request) error {
+	f, err := ParseFilter(r)
+	if err != nil {
+		return err
+	}
+
+	wt, err := newWatcher()
+	if err != nil {
+		return err
+	}
+
+	sub, err := subscribe()
+	if err != nil {
+		log.Errorf("Failed to subscribe to netlink: %s", err)
+		return err
+	}
+	defer sub.close()
+
+	stream, err := share.NewEventStream(w, r)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	keepalive := time.NewTicker(share.StreamKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		var events []*Event
+
+		select {
+		case <-stream.Done():
+			return nil
+		case <-keepalive.C:
+			if stream.Ping() != nil {
+				return nil
+			}
+		case u, ok := <-sub.links:
+			if !ok {
+				return nil
+			}
+
+			events = wt.linkEvents(u)
+		case u, ok := <-sub.addrs:
+			if !ok {
+				return nil
+			}
+
+			events = []*Event{wt.addressEvent(u)}
+		case u, ok := <-sub.routes:
+			if !ok {
+				return nil
+			}
+
+			events = []*Event{wt.routeEvent(u)}
+		case u, ok := <-sub.neighs:
+			if !ok {
+				return nil
+			}
+
+			events = []*Event{wt.neighborEvent(u)}
+		}
+
+		for _, e := range events {
+			if !f.matches(e) {
+				continue
+			}
+
+			if stream.Send(e.Type, e) != nil {
+				return nil
+			}
+		}
+	}
+}
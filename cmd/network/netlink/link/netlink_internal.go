@@ -4,12 +4,16 @@ package link
 
 import (
 	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/RestGW/api-routerd/cmd/share"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/vishvananda/netlink"
 )
 
-func (req *Link) setMasterBridge() error {
+func (req *Link) setMasterBridge(r *http.Request) error {
 	bridge, err := netlink.LinkByName(req.Link)
 	if err != nil {
 		log.Errorf("Failed to find bridge link %s: %v", req.Link, err)
@@ -19,7 +23,7 @@ func (req *Link) setMasterBridge() error {
 	br, b := bridge.(*netlink.Bridge)
 	if !b {
 		log.Errorf("Link '%s'is not a bridge: %v", req.Link, err)
-		return fmt.Errorf("Link is not a bridge")
+		return share.BadRequest(fmt.Errorf("Link is not a bridge"))
 	}
 
 	for _, n := range req.Enslave {
@@ -29,16 +33,21 @@ func (req *Link) setMasterBridge() error {
 			continue
 		}
 
+		prev := link.Attrs().MasterIndex
+
 		err = netlink.LinkSetMaster(link, br)
 		if err != nil {
 			log.Errorf("Failed to set link %s master device %s: %v", n, req.Link, err)
+			continue
 		}
+
+		undoMaster(r, n, prev)
 	}
 
 	return nil
 }
 
-func (req *Link) createBridge() error {
+func (req *Link) createBridge(r *http.Request) error {
 	_, err := netlink.LinkByName(req.Link)
 	if err == nil {
 		log.Infof("Bridge link %s exists. Using the bridge", req.Link)
@@ -55,13 +64,15 @@ func (req *Link) createBridge() error {
 			return err
 		}
 
+		undoCreate(r, req.Link)
+
 		log.Debugf("Successfully create bridge link: %s", req.Link)
 	}
 
-	return req.setMasterBridge()
+	return req.setMasterBridge(r)
 }
 
-func (req *Link) setMasterBond() error {
+func (req *Link) setMasterBond(r *http.Request) error {
 	bond, err := netlink.LinkByName(req.Link)
 	if err != nil {
 		log.Errorf("Failed to find bond link %s: %v", req.Link, err)
@@ -75,16 +86,21 @@ func (req *Link) setMasterBond() error {
 			continue
 		}
 
+		prev := link.Attrs().MasterIndex
+
 		err = netlink.LinkSetBondSlave(link, &netlink.Bond{LinkAttrs: *bond.Attrs()})
 		if err != nil {
 			log.Errorf("Failed to set link %s master device %s: %v", n, req.Link, err)
+			continue
 		}
+
+		undoMaster(r, n, prev)
 	}
 
 	return nil
 }
 
-func (req *Link) createBond() error {
+func (req *Link) createBond(r *http.Request) error {
 	_, err := netlink.LinkByName(req.Link)
 	if err == nil {
 		log.Infof("Bond link %s exists. Using the bond", req.Link)
@@ -103,10 +119,12 @@ func (req *Link) createBond() error {
 			return err
 		}
 
+		undoCreate(r, req.Link)
+
 		log.Debugf("Successfully create bond link: %s", req.Link)
 	}
 
-	return req.setMasterBond()
+	return req.setMasterBond(r)
 }
 
 func setUp(link string) error {
@@ -156,3 +174,116 @@ func setMTU(link string, mtu int) error {
 
 	return nil
 }
+
+func upDown(up bool) string {
+	if up {
+		return "up"
+	}
+
+	return "down"
+}
+
+// undoCreate registers deleting a link the request created
+func undoCreate(r *http.Request, name string) {
+	share.OnRollback(r, "delete link "+name, func() error {
+		l, err := netlink.LinkByName(name)
+		if err != nil {
+			return err
+		}
+
+		return netlink.LinkDel(l)
+	})
+}
+
+// undoMaster registers giving a link its previous master back, none for 0
+func undoMaster(r *http.Request, name string, master int) {
+	share.OnRollback(r, "set master of link "+name+" back", func() error {
+		l, err := netlink.LinkByName(name)
+		if err != nil {
+			return err
+		}
+
+		if master == 0 {
+			return netlink.LinkSetNoMaster(l)
+		}
+
+		return netlink.LinkSetMasterByIndex(l, master)
+	})
+}
+
+func slaveNames(master netlink.Link) ([]string, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, err
+	}
+
+	var slaves []string
+	for _, l := range links {
+		if l.Attrs().MasterIndex == master.Attrs().Index {
+			slaves = append(slaves, l.Attrs().Name)
+		}
+	}
+
+	return slaves, nil
+}
+
+// recreatable the link kinds a deleted link can be created again of
+func recreatable(l netlink.Link) bool {
+	switch l.Type() {
+	case "bridge", "bond", "dummy":
+		return true
+	}
+
+	return false
+}
+
+// recreate a deleted bridge, bond or dummy with its MTU, slaves and up state
+func recreate(l netlink.Link, slaves []string) error {
+	attrs := netlink.NewLinkAttrs()
+	attrs.Name = l.Attrs().Name
+	attrs.MTU = l.Attrs().MTU
+
+	var link netlink.Link
+	switch l.Type() {
+	case "bridge":
+		link = &netlink.Bridge{LinkAttrs: attrs}
+	case "bond":
+		bond := netlink.NewLinkBond(attrs)
+		if b, ok := l.(*netlink.Bond); ok {
+			bond.Mode = b.Mode
+		}
+		link = bond
+	case "dummy":
+		link = &netlink.Dummy{LinkAttrs: attrs}
+	default:
+		return fmt.Errorf("Can not recreate %s link '%s'", l.Type(), attrs.Name)
+	}
+
+	err := netlink.LinkAdd(link)
+	if err != nil {
+		return err
+	}
+
+	master, err := netlink.LinkByName(attrs.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range slaves {
+		s, err := netlink.LinkByName(n)
+		if err != nil {
+			return err
+		}
+
+		err = netlink.LinkSetMasterByIndex(s, master.Attrs().Index)
+		if err != nil {
+			return err
+		}
+	}
+
+	if l.Attrs().Flags&net.FlagUp != 0 {
+		return netlink.LinkSetUp(master)
+	}
+
+	return nil
+}
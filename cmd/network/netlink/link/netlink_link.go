@@ -4,6 +4,8 @@ package link
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -36,12 +38,18 @@ func DecodeJSONRequest(r *http.Request) (Link, error) {
 }
 
 //Set sets link status/attribute
-func (link *Link) Set() error {
+func (link *Link) Set(r *http.Request) error {
+	l, err := netlink.LinkByName(link.Link)
+	if err != nil {
+		return err
+	}
+	prev := *l.Attrs()
+
 	switch link.Action {
 	case "set-link-up":
-		return setUp(link.Link)
+		err = setUp(link.Link)
 	case "set-link-down":
-		return setDown(link.Link)
+		err = setDown(link.Link)
 	case "set-link-mtu":
 
 		mtu, err := strconv.ParseInt(strings.TrimSpace(link.MTU), 10, 64)
@@ -49,9 +57,32 @@ func (link *Link) Set() error {
 			return err
 		}
 
-		return setMTU(link.Link, int(mtu))
+		err = setMTU(link.Link, int(mtu))
+		if err != nil {
+			return err
+		}
+
+		share.OnRollback(r, fmt.Sprintf("set MTU of link %s back to %d", link.Link, prev.MTU), func() error {
+			return setMTU(link.Link, prev.MTU)
+		})
+
+		return nil
+	default:
+		return nil
 	}
 
+	if err != nil {
+		return err
+	}
+
+	share.OnRollback(r, "set link "+link.Link+" back "+upDown(prev.Flags&net.FlagUp != 0), func() error {
+		if prev.Flags&net.FlagUp != 0 {
+			return setUp(link.Link)
+		}
+
+		return setDown(link.Link)
+	})
+
 	return nil
 }
 
@@ -76,27 +107,38 @@ func (link *Link) Get(rw http.ResponseWriter) error {
 }
 
 //Delete remove a netdev
-func (link *Link) Delete() error {
+func (link *Link) Delete(r *http.Request) error {
 	l, err := netlink.LinkByName(link.Link)
 	if err != nil {
 		return err
 	}
 
+	slaves, err := slaveNames(l)
+	if err != nil {
+		return err
+	}
+
 	err = netlink.LinkDel(l)
 	if err != nil {
 		return err
 	}
 
+	if recreatable(l) {
+		share.OnRollback(r, "recreate link "+link.Link, func() error {
+			return recreate(l, slaves)
+		})
+	}
+
 	return nil
 }
 
 //Create create netdevs
-func (link *Link) Create() error {
+func (link *Link) Create(r *http.Request) error {
 	switch link.Action {
 	case "add-link-bridge":
-		return link.createBridge()
+		return link.createBridge(r)
 	case "add-link-bond":
-		return link.createBond()
+		return link.createBond(r)
 	}
 
 	return nil
@@ -4,9 +4,12 @@ import (
 	"net/http"
 
 	"github.com/RestGW/api-routerd/cmd/network/netlink/address"
+	"github.com/RestGW/api-routerd/cmd/network/netlink/events"
 	"github.com/RestGW/api-routerd/cmd/network/netlink/link"
 	"github.com/RestGW/api-routerd/cmd/network/netlink/route"
+	"github.com/RestGW/api-routerd/cmd/share"
 	"github.com/gorilla/mux"
+	nl "github.com/vishvananda/netlink"
 )
 
 func routerLinkGet(rw http.ResponseWriter, r *http.Request) {
@@ -22,8 +25,10 @@ func routerLinkGet(rw http.ResponseWriter, r *http.Request) {
 
 		err := l.Get(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -32,14 +37,16 @@ func routerLinkAdd(rw http.ResponseWriter, r *http.Request) {
 	case "POST":
 		link, err := link.DecodeJSONRequest(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
-		err = link.Create()
+		err = link.Create(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -48,14 +55,16 @@ func routerLinkDelete(rw http.ResponseWriter, r *http.Request) {
 	case "DELETE":
 		link, err := link.DecodeJSONRequest(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
-		err = link.Delete()
+		err = link.Delete(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -64,14 +73,16 @@ func routerLinkSet(rw http.ResponseWriter, r *http.Request) {
 	case "PUT":
 		link, err := link.DecodeJSONRequest(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
-		err = link.Set()
+		err = link.Set(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -86,7 +97,12 @@ func routerGetAddress(rw http.ResponseWriter, r *http.Request) {
 			Link: link,
 		}
 
-		a.Get(rw)
+		err := a.Get(rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -96,15 +112,17 @@ func routerAddAddress(rw http.ResponseWriter, r *http.Request) {
 
 		address, err := address.DecodeJSONRequest(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
-		err = address.Add()
+		err = address.Add(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -114,42 +132,46 @@ func routerDeleteAddress(rw http.ResponseWriter, r *http.Request) {
 
 		address, err := address.DecodeJSONRequest(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
-		err = address.Del()
+		err = address.Del(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
 func routerAddRoute(rw http.ResponseWriter, r *http.Request) {
 	route, err := route.DecodeJSONRequest(r)
 	if err != nil {
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		share.HTTPError(rw, share.BadRequest(err))
 		return
 	}
 
 	switch r.Method {
 	case "POST":
-		err = route.Configure()
+		err = route.Configure(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
 	case "PUT":
-		err = route.Configure()
+		err = route.Configure(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -158,17 +180,19 @@ func routerDeleteRoute(rw http.ResponseWriter, r *http.Request) {
 	case "DELETE":
 		route, err := route.DecodeJSONRequest(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
-		err = route.DeleteGateWay()
+		err = route.DeleteGateWay(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -184,29 +208,60 @@ func routerGetRoute(rw http.ResponseWriter, r *http.Request) {
 
 		err := r.Get(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
 //RegisterRouterNetlink register with mux
+func routerGetEvents(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		err := events.Stream(rw, r)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
 func RegisterRouterNetlink(n *mux.Router) {
 	// Link
-	n.HandleFunc("/link/set", routerLinkSet)
-	n.HandleFunc("/link/add", routerLinkAdd)
-	n.HandleFunc("/link/delete", routerLinkDelete)
-	n.HandleFunc("/link/get/{link}", routerLinkGet)
-	n.HandleFunc("/link/get", routerLinkGet)
+	share.Describe(n.HandleFunc("/link/set", routerLinkSet),
+		share.Op("PUT", "Set a link up, down or its MTU", link.Link{}, nil).Confirmable())
+	share.Describe(n.HandleFunc("/link/add", routerLinkAdd),
+		share.Op("POST", "Create a bridge or bond and enslave links", link.Link{}, nil).Confirmable())
+	share.Describe(n.HandleFunc("/link/delete", routerLinkDelete),
+		share.Op("DELETE", "Delete a link", link.Link{}, nil).Confirmable())
+	share.Describe(n.HandleFunc("/link/get/{link}", routerLinkGet),
+		share.Op("GET", "Get a link", nil, map[string]interface{}{}))
+	share.Describe(n.HandleFunc("/link/get", routerLinkGet),
+		share.Op("GET", "List links", nil, []map[string]interface{}{}))
 
 	// Address
-	n.HandleFunc("/address/add", routerAddAddress)
-	n.HandleFunc("/address/delete", routerDeleteAddress)
-	n.HandleFunc("/address/get", routerGetAddress)
-	n.HandleFunc("/address/get/{link}", routerGetAddress)
+	share.Describe(n.HandleFunc("/address/add", routerAddAddress),
+		share.Op("POST", "Add an address to a link", address.Address{}, nil).Confirmable())
+	share.Describe(n.HandleFunc("/address/delete", routerDeleteAddress),
+		share.Op("DELETE", "Delete an address from a link", address.Address{}, nil).Confirmable())
+	share.Describe(n.HandleFunc("/address/get", routerGetAddress),
+		share.Op("GET", "List addresses", nil, []nl.Addr{}))
+	share.Describe(n.HandleFunc("/address/get/{link}", routerGetAddress),
+		share.Op("GET", "List addresses of a link", nil, []nl.Addr{}))
 
 	// Route
-	n.HandleFunc("/route/add", routerAddRoute)
-	n.HandleFunc("/route/del", routerDeleteRoute)
-	n.HandleFunc("/route/get/{link}", routerGetRoute)
+	share.Describe(n.HandleFunc("/route/add", routerAddRoute),
+		share.Op("POST", "Add or replace the default gateway", route.Route{}, nil).Confirmable(),
+		share.Op("PUT", "Add or replace the default gateway", route.Route{}, nil).Confirmable())
+	share.Describe(n.HandleFunc("/route/del", routerDeleteRoute),
+		share.Op("DELETE", "Delete the default gateway", route.Route{}, nil).Confirmable())
+	share.Describe(n.HandleFunc("/route/get/{link}", routerGetRoute),
+		share.Op("GET", "List routes", nil, []nl.Route{}))
+
+	// Events
+	share.Describe(n.HandleFunc("/events", routerGetEvents),
+		share.Op("GET", "Stream link, address, route and neighbor changes over SSE or WebSocket, filtered by ?link= and ?type=", nil, events.Event{}))
 }
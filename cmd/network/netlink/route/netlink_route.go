@@ -36,7 +36,7 @@ func DecodeJSONRequest(r *http.Request) (Route, error) {
 }
 
 //AddDefaultGateWay add a default GW
-func (route *Route) AddDefaultGateWay() error {
+func (route *Route) AddDefaultGateWay(r *http.Request) error {
 	link, err := netlink.LinkByName(route.Link)
 	if err != nil {
 		log.Errorf("Failed to find link %s: %v", err, route.Link)
@@ -73,11 +73,15 @@ func (route *Route) AddDefaultGateWay() error {
 		return err
 	}
 
+	share.OnRollback(r, "delete default gateway "+route.Gateway, func() error {
+		return netlink.RouteDel(rt)
+	})
+
 	return nil
 }
 
 //ReplaceDefaultGateWay replace default GW with new one
-func (route *Route) ReplaceDefaultGateWay() error {
+func (route *Route) ReplaceDefaultGateWay(r *http.Request) error {
 	link, err := netlink.LinkByName(route.Link)
 	if err != nil {
 		return err
@@ -107,17 +111,54 @@ func (route *Route) ReplaceDefaultGateWay() error {
 		Flags:     onlink,
 	}
 
+	prev, err := defaultRoute(ipAddr)
+	if err != nil {
+		return err
+	}
+
 	err = netlink.RouteReplace(rt)
 	if err != nil {
 		log.Errorf("Failed to replace default GateWay address %s: %v", route.Gateway, err)
 		return err
 	}
 
+	if prev == nil {
+		share.OnRollback(r, "delete default gateway "+route.Gateway, func() error {
+			return netlink.RouteDel(rt)
+		})
+	} else {
+		share.OnRollback(r, "restore default gateway "+prev.Gw.String(), func() error {
+			return netlink.RouteReplace(prev)
+		})
+	}
+
 	return nil
 }
 
+// defaultRoute the default route of the main table in the family of gw, a
+// replace or delete of the gateway changes it
+func defaultRoute(gw net.IP) (*netlink.Route, error) {
+	family := netlink.FAMILY_V6
+	if gw.To4() != nil {
+		family = netlink.FAMILY_V4
+	}
+
+	routes, err := netlink.RouteList(nil, family)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rt := range routes {
+		if rt.Dst == nil && rt.Priority == 0 && (rt.Table == 0 || rt.Table == syscall.RT_TABLE_MAIN) {
+			return &rt, nil
+		}
+	}
+
+	return nil, nil
+}
+
 //DeleteGateWay remove a gateway
-func (route *Route) DeleteGateWay() error {
+func (route *Route) DeleteGateWay(r *http.Request) error {
 	link, err := netlink.LinkByName(route.Link)
 	if err != nil {
 		log.Errorf("Failed to delete default gateway %s: %v", link, err)
@@ -138,11 +179,24 @@ func (route *Route) DeleteGateWay() error {
 			Gw:        ipAddr,
 		}
 
+		prev, err := defaultRoute(ipAddr)
+		if err != nil {
+			return err
+		}
+
 		err = netlink.RouteDel(rt)
 		if err != nil {
 			log.Errorf("Failed to delete default GateWay address %s: %v", ipAddr, err)
 			return err
 		}
+
+		if prev != nil && prev.Gw.Equal(ipAddr) {
+			rt = prev
+		}
+
+		share.OnRollback(r, "add default gateway "+route.Gateway, func() error {
+			return netlink.RouteAdd(rt)
+		})
 		break
 	}
 
@@ -160,12 +214,12 @@ func (route *Route) Get(rw http.ResponseWriter) error {
 }
 
 //Configure routes
-func (route *Route) Configure() error {
+func (route *Route) Configure(r *http.Request) error {
 	switch route.Action {
 	case "add-default-gw":
-		return route.AddDefaultGateWay()
+		return route.AddDefaultGateWay(r)
 	case "replace-default-gw":
-		return route.ReplaceDefaultGateWay()
+		return route.ReplaceDefaultGateWay(r)
 	}
 
 	return nil
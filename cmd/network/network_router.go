@@ -3,6 +3,8 @@
 package network
 
 import (
+	"github.com/RestGW/api-routerd/cmd/conf"
+	"github.com/RestGW/api-routerd/cmd/network/confirm"
 	"github.com/RestGW/api-routerd/cmd/network/ethtool"
 	"github.com/RestGW/api-routerd/cmd/network/netlink"
 	"github.com/RestGW/api-routerd/cmd/network/networkd"
@@ -14,7 +16,19 @@ import (
 func RegisterRouterNetwork(router *mux.Router) {
 	n := router.PathPrefix("/network").Subrouter()
 
-	netlink.RegisterRouterNetlink(n)
-	networkd.RegisterRouterNetworkd(n)
-	ethtool.RegisterRouterEthtool(n)
+	// commit-confirm of netlink and networkd changes
+	n.Use(confirm.Middleware)
+	confirm.RegisterRouterConfirm(n)
+
+	if conf.ModuleEnabled("netlink") {
+		netlink.RegisterRouterNetlink(n)
+	}
+
+	if conf.ModuleEnabled("networkd") {
+		networkd.RegisterRouterNetworkd(n)
+	}
+
+	if conf.ModuleEnabled("ethtool") {
+		ethtool.RegisterRouterEthtool(n)
+	}
 }
@@ -192,20 +192,27 @@ func parseJSONFromHTTPReq(req *http.Request) error {
 	}
 
 	link := new(Link)
-	json.Unmarshal([]byte(body), &link)
+	err = json.Unmarshal([]byte(body), &link)
+	if err != nil {
+		return share.BadRequest(err)
+	}
 
 	matchConfig := link.createMatchSectionConfig()
 	linkConfig := link.createLinkSectionConfig()
 
 	config := []string{matchConfig, linkConfig}
 
+	if !share.IsValidFileName(link.Name) {
+		return share.BadRequest(fmt.Errorf("Invalid Name '%s'", link.Name))
+	}
+
 	unitName := fmt.Sprintf("00-%s.link", link.Name)
 	unitPath := filepath.Join(networkdUnitPath, unitName)
 
-	return share.WriteFullFile(unitPath, config)
+	return share.WriteConfigFile(req, unitPath, share.RenderLines(config), 0644)
 }
 
 //CreateFile generate .link file
-func CreateFile(rw http.ResponseWriter, req *http.Request) {
-	parseJSONFromHTTPReq(req)
+func CreateFile(rw http.ResponseWriter, req *http.Request) error {
+	return parseJSONFromHTTPReq(req)
 }
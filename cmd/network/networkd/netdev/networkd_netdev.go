@@ -283,20 +283,27 @@ func parseJSONFromHTTPReq(req *http.Request) error {
 	}
 
 	netdev := new(NetDev)
-	json.Unmarshal([]byte(body), &netdev)
+	err = json.Unmarshal([]byte(body), &netdev)
+	if err != nil {
+		return share.BadRequest(err)
+	}
 
 	netdevConfig := netdev.CreateNetDevSectionConfig()
 	config := []string{netdevConfig}
 
 	fmt.Println(config)
 
+	if !share.IsValidFileName(netdev.Name) {
+		return share.BadRequest(fmt.Errorf("Invalid Name '%s'", netdev.Name))
+	}
+
 	unitName := fmt.Sprintf("25-%s.netdev", netdev.Name)
 	unitPath := filepath.Join(networkdUnitPath, unitName)
 
-	return share.WriteFullFile(unitPath, config)
+	return share.WriteConfigFile(req, unitPath, share.RenderLines(config), 0644)
 }
 
 //CreateFile generate .netdev
-func CreateFile(rw http.ResponseWriter, req *http.Request) {
-	parseJSONFromHTTPReq(req)
+func CreateFile(rw http.ResponseWriter, req *http.Request) error {
+	return parseJSONFromHTTPReq(req)
 }
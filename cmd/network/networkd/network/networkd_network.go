@@ -700,10 +700,16 @@ func parseJSONfromHTTPReq(req *http.Request) error {
 		return err
 	}
 
-	json.Unmarshal([]byte(body), &configs)
+	err = json.Unmarshal([]byte(body), &configs)
+	if err != nil {
+		return share.BadRequest(err)
+	}
 
 	network := new(Network)
-	json.Unmarshal([]byte(body), &network)
+	err = json.Unmarshal([]byte(body), &network)
+	if err != nil {
+		return share.BadRequest(err)
+	}
 
 	matchConfig := network.createMatchSectionConfig()
 	networkConfig := network.createNetworkSectionConfig()
@@ -714,13 +720,17 @@ func parseJSONfromHTTPReq(req *http.Request) error {
 
 	config := []string{matchConfig, networkConfig, addressConfig, routeConfig, ruleConfig, dhcpConfig}
 
+	if !share.IsValidFileName(network.ConfFile) {
+		return share.BadRequest(fmt.Errorf("Invalid ConfFile '%s'", network.ConfFile))
+	}
+
 	unitName := fmt.Sprintf("25-%s.network", network.ConfFile)
 	unitPath := filepath.Join(networkdUnitPath, unitName)
 
-	return share.WriteFullFile(unitPath, config)
+	return share.WriteConfigFile(req, unitPath, share.RenderLines(config), 0644)
 }
 
 //CreateFile generate .network
-func CreateFile(rw http.ResponseWriter, req *http.Request) {
-	parseJSONfromHTTPReq(req)
+func CreateFile(rw http.ResponseWriter, req *http.Request) error {
+	return parseJSONfromHTTPReq(req)
 }
@@ -20,7 +20,7 @@ type Networkctl struct {
 func (n *Networkctl) NetworkctlGet(rw http.ResponseWriter) error {
 	link := share.LinkExists(n.Link)
 	if !link {
-		return fmt.Errorf("Failed to find link: %s", n.Link)
+		return share.NotFound(fmt.Errorf("Failed to find link: %s", n.Link))
 	}
 
 	switch n.Verb {
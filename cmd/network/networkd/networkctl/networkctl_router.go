@@ -5,6 +5,8 @@ package networkctl
 import (
 	"net/http"
 
+	"github.com/RestGW/api-routerd/cmd/share"
+
 	"github.com/gorilla/mux"
 )
 
@@ -23,9 +25,11 @@ func routerNetworkctlGet(rw http.ResponseWriter, r *http.Request) {
 
 		err := n.NetworkctlGet(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -33,7 +37,10 @@ func routerNetworkctlGet(rw http.ResponseWriter, r *http.Request) {
 func RegisterRouterNetworkctl(r *mux.Router) {
 
 	n := r.PathPrefix("/networkctl").Subrouter().StrictSlash(false)
-	n.HandleFunc("", routerNetworkctlGet)
-	n.HandleFunc("/{verb}", routerNetworkctlGet)
-	n.HandleFunc("/{verb}/{link}", routerNetworkctlGet)
+	share.Describe(n.HandleFunc("", routerNetworkctlGet),
+		share.Op("GET", "List links", nil, []Response{}))
+	share.Describe(n.HandleFunc("/{verb}", routerNetworkctlGet),
+		share.Op("GET", "Run networkctl lldp", nil, []ResponseLLDP{}))
+	share.Describe(n.HandleFunc("/{verb}/{link}", routerNetworkctlGet),
+		share.Op("GET", "Run networkctl status on a link", nil, ResponseStatus{}))
 }
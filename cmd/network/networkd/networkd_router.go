@@ -5,10 +5,12 @@ package networkd
 import (
 	"net/http"
 
+	"github.com/RestGW/api-routerd/cmd/conf"
 	"github.com/RestGW/api-routerd/cmd/network/networkd/link"
 	"github.com/RestGW/api-routerd/cmd/network/networkd/netdev"
 	"github.com/RestGW/api-routerd/cmd/network/networkd/network"
 	"github.com/RestGW/api-routerd/cmd/network/networkd/networkctl"
+	"github.com/RestGW/api-routerd/cmd/share"
 
 	"github.com/gorilla/mux"
 )
@@ -16,21 +18,36 @@ import (
 func routerConfigureNetworkdLink(rw http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "POST":
-		link.CreateFile(rw, r)
+		err := link.CreateFile(rw, r)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
 func routerConfigureNetworkdNetDev(rw http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "POST":
-		netdev.CreateFile(rw, r)
+		err := netdev.CreateFile(rw, r)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
 func routerConfigureNetworkdNetwork(rw http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "POST":
-		network.CreateFile(rw, r)
+		err := network.CreateFile(rw, r)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -41,10 +58,15 @@ func RegisterRouterNetworkd(router *mux.Router) {
 	n := router.PathPrefix("/networkd").Subrouter().StrictSlash(false)
 
 	// systemd-networkd
-	n.HandleFunc("/network", routerConfigureNetworkdNetwork)
-	n.HandleFunc("/netdev", routerConfigureNetworkdNetDev)
-	n.HandleFunc("/link", routerConfigureNetworkdLink)
+	share.Describe(n.HandleFunc("/network", routerConfigureNetworkdNetwork),
+		share.Op("POST", "Write a .network file", network.Network{}, nil).Previewable().Confirmable())
+	share.Describe(n.HandleFunc("/netdev", routerConfigureNetworkdNetDev),
+		share.Op("POST", "Write a .netdev file", netdev.NetDev{}, nil).Previewable().Confirmable())
+	share.Describe(n.HandleFunc("/link", routerConfigureNetworkdLink),
+		share.Op("POST", "Write a .link file", link.Link{}, nil).Previewable().Confirmable())
 
 	// networkctl
-	networkctl.RegisterRouterNetworkctl(n)
+	if conf.ModuleEnabled("networkctl") {
+		networkctl.RegisterRouterNetworkctl(n)
+	}
 }
@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugins
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"plugin"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// symbols looked up in every plugin
+	registerSymbol = "Register"
+	versionSymbol  = "Version"
+)
+
+//Plugin a Go plugin found in the plugins directory. Error is set when the
+//plugin failed to load or to register and its routes are not mounted
+type Plugin struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Path    string `json:"path"`
+	Loaded  bool   `json:"loaded"`
+	Error   string `json:"error,omitempty"`
+
+	register func(*mux.Router)
+}
+
+var plugins = struct {
+	sync.RWMutex
+	list []*Plugin
+}{}
+
+func (p *Plugin) loaded() bool {
+	plugins.RLock()
+	defer plugins.RUnlock()
+
+	return p.Loaded
+}
+
+func (p *Plugin) fail(err error) {
+	plugins.Lock()
+	defer plugins.Unlock()
+
+	p.Loaded = false
+	p.Error = err.Error()
+}
+
+func (p *Plugin) open() error {
+	so, err := plugin.Open(p.Path)
+	if err != nil {
+		return err
+	}
+
+	sym, err := so.Lookup(registerSymbol)
+	if err != nil {
+		return err
+	}
+
+	register, ok := sym.(func(*mux.Router))
+	if !ok {
+		return fmt.Errorf("Symbol %s is %T, expected func(*mux.Router)", registerSymbol, sym)
+	}
+
+	p.register = register
+
+	// Version is an optional string variable
+	sym, err = so.Lookup(versionSymbol)
+	if err == nil {
+		if v, ok := sym.(*string); ok {
+			p.Version = *v
+		}
+	}
+
+	return nil
+}
+
+//Load opens every .so file in dir. A plugin that fails to open is
+//reported by List and skipped, the others are still mounted
+func Load(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var list []*Plugin
+	for _, f := range files {
+		if f.IsDir() || path.Ext(f.Name()) != ".so" {
+			continue
+		}
+
+		p := &Plugin{
+			Name: strings.TrimSuffix(f.Name(), ".so"),
+			Path: path.Join(dir, f.Name()),
+		}
+
+		err := p.open()
+		if err != nil {
+			log.Errorf("Failed to load plugin %s: %s", p.Path, err)
+			p.Error = err.Error()
+		} else {
+			log.Infof("Loaded plugin %s version '%s'", p.Name, p.Version)
+			p.Loaded = true
+		}
+
+		list = append(list, p)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	plugins.Lock()
+	plugins.list = list
+	plugins.Unlock()
+
+	return nil
+}
+
+//List the plugins found by Load
+func List() []Plugin {
+	plugins.RLock()
+	defer plugins.RUnlock()
+
+	list := make([]Plugin, 0, len(plugins.list))
+	for _, p := range plugins.list {
+		list = append(list, *p)
+	}
+
+	return list
+}
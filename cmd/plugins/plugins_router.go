@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package plugins
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func routerGetPlugins(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		err := share.JSONResponse(List(), rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+// isolate a panic in a plugin handler fails the request, not api-routerd.
+// Routes of a plugin whose Register failed half way are not served
+func (p *Plugin) isolate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !p.loaded() {
+			share.HTTPError(rw, share.NewError(http.StatusServiceUnavailable, "Plugin '%s' is not loaded", p.Name))
+			return
+		}
+
+		defer func() {
+			if e := recover(); e != nil {
+				log.Errorf("Plugin %s panicked on %s %s: %v\n%s", p.Name, r.Method, r.URL.Path, e, debug.Stack())
+				share.HTTPError(rw, fmt.Errorf("Plugin '%s' failed", p.Name))
+			}
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// mount calls the Register symbol of the plugin on its own subrouter
+func (p *Plugin) mount(router *mux.Router) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("Register panicked: %v", e)
+		}
+	}()
+
+	s := router.PathPrefix("/" + p.Name).Subrouter()
+	s.Use(p.isolate)
+
+	p.register(s)
+
+	return nil
+}
+
+//RegisterRouterPlugins register the loaded plugins and their list with mux
+func RegisterRouterPlugins(router *mux.Router) {
+	n := router.PathPrefix("/plugins").Subrouter().StrictSlash(false)
+
+	share.Describe(n.HandleFunc("", routerGetPlugins),
+		share.Op("GET", "List the plugins with their versions", nil, []Plugin{}))
+
+	plugins.RLock()
+	list := plugins.list
+	plugins.RUnlock()
+
+	for _, p := range list {
+		if !p.loaded() {
+			continue
+		}
+
+		err := p.mount(n)
+		if err != nil {
+			log.Errorf("Failed to register plugin %s: %s", p.Name, err)
+			p.fail(err)
+		}
+	}
+}
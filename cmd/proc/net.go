@@ -50,7 +50,7 @@ func (req *SysNet) getPath() (string, error) {
 		}
 		break
 	default:
-		return "", errors.New("Path not found")
+		return "", share.NotFound(errors.New("Path not found"))
 	}
 
 	return procPath, nil
@@ -79,11 +79,15 @@ func (req *SysNet) GetSysNet(rw http.ResponseWriter) error {
 }
 
 //SetSysNet sets a value to proc
-func (req *SysNet) SetSysNet(rw http.ResponseWriter) error {
+func (req *SysNet) SetSysNet(rw http.ResponseWriter, r *http.Request) error {
 	path, err := req.getPath()
 	if err != nil {
 		return err
 	}
 
-	return share.WriteOneLineFile(path, req.Value)
+	if p := share.DryRun(r); p != nil {
+		return p.Add(path, req.Value+"\n")
+	}
+
+	return share.SetOneLineFile(r, path, req.Value)
 }
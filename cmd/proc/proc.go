@@ -127,7 +127,7 @@ func GetNetStat(rw http.ResponseWriter, protocol string) error {
 func GetNetStatPid(rw http.ResponseWriter, protocol string, process string) error {
 	pid, err := strconv.ParseInt(process, 10, 32)
 	if err != nil || protocol == "" || pid == 0 {
-		return errors.New("Can't parse request")
+		return share.BadRequest(errors.New("Can't parse request"))
 	}
 
 	conn, err := net.ConnectionsPid(protocol, int32(pid))
@@ -6,7 +6,15 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/RestGW/api-routerd/cmd/share"
+
 	"github.com/gorilla/mux"
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/disk"
+	"github.com/shirou/gopsutil/host"
+	"github.com/shirou/gopsutil/load"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/shirou/gopsutil/net"
 )
 
 //Info Json request
@@ -21,9 +29,11 @@ func routerGetProcNetDev(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetNetDev(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -32,9 +42,11 @@ func routerGetProcVersion(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetVersion(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -43,9 +55,11 @@ func routerGetProcPlatformInformation(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetPlatformInformation(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -54,9 +68,11 @@ func routerGetProcVirtualization(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetVirtualization(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -65,9 +81,11 @@ func routerGetProcUserStat(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetUserStat(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -76,9 +94,11 @@ func routerGetProcTemperatureStat(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetTemperatureStat(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -90,9 +110,11 @@ func routerGetProcNetStat(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetNetStat(rw, protocol)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -105,9 +127,11 @@ func routerGetProcPidNetStat(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetNetStatPid(rw, protocol, pid)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -116,9 +140,11 @@ func routerGetProcInterfaceStat(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetInterfaceStat(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -127,9 +153,11 @@ func routerGetProcProtoCountersStat(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetProtoCountersStat(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -138,9 +166,11 @@ func routerGetProcGetSwapMemoryStat(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetSwapMemoryStat(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -149,9 +179,11 @@ func routerGetProcVirtualMemoryStat(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetVirtualMemoryStat(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -160,9 +192,11 @@ func routerGetProcCPUInfo(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetCPUInfo(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -171,9 +205,11 @@ func routerGetProcCPUTimeStat(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetCPUTimeStat(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -182,9 +218,11 @@ func routerGetProcAvgStat(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetAvgStat(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -204,17 +242,20 @@ func configureProcSysVM(rw http.ResponseWriter, r *http.Request) {
 
 		err = json.NewDecoder(r.Body).Decode(&v)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusBadRequest)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
 		vm.Value = v.Value
-		err = vm.SetVM(rw)
+		err = vm.SetVM(rw, r)
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
+		return
 	}
 
 	if err != nil {
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		share.HTTPError(rw, err)
 	}
 }
 
@@ -226,7 +267,7 @@ func configureProcSysNet(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := proc.GetSysNet(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
 	case "PUT":
@@ -234,16 +275,18 @@ func configureProcSysNet(rw http.ResponseWriter, r *http.Request) {
 		v := new(Info)
 		err := json.NewDecoder(r.Body).Decode(&v)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusBadRequest)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
 		proc.Value = v.Value
-		err = proc.SetSysNet(rw)
+		err = proc.SetSysNet(rw, r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -252,9 +295,11 @@ func routerGetProcMisc(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetMisc(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -263,9 +308,11 @@ func routerGetProcNetArp(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetNetArp(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -274,9 +321,11 @@ func routerGetProcModules(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetModules(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -290,9 +339,11 @@ func routerGetProcProcess(rw http.ResponseWriter, r *http.Request) {
 
 		err := GetProcessInfo(rw, pid, property)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -301,8 +352,10 @@ func routerGetPartitions(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetPartitions(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -311,8 +364,10 @@ func routerGetIOCounters(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetIOCounters(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -321,8 +376,10 @@ func routerGetDiskUsage(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetDiskUsage(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -330,28 +387,54 @@ func routerGetDiskUsage(rw http.ResponseWriter, r *http.Request) {
 func RegisterRouterProc(router *mux.Router) {
 	n := router.PathPrefix("/proc").Subrouter().StrictSlash(false)
 
-	n.HandleFunc("/avgstat", routerGetProcAvgStat)
-	n.HandleFunc("/cpuinfo", routerGetProcCPUInfo)
-	n.HandleFunc("/cputimestat", routerGetProcCPUTimeStat)
-	n.HandleFunc("/diskusage", routerGetDiskUsage)
-	n.HandleFunc("/interface-stat", routerGetProcInterfaceStat)
-	n.HandleFunc("/iocounters", routerGetIOCounters)
-	n.HandleFunc("/misc", routerGetProcMisc)
-	n.HandleFunc("/modules", routerGetProcModules)
-	n.HandleFunc("/net/arp", routerGetProcNetArp)
-	n.HandleFunc("/netdev", routerGetProcNetDev)
-	n.HandleFunc("/netstat/{protocol}", routerGetProcNetStat)
-	n.HandleFunc("/partitions", routerGetPartitions)
-	n.HandleFunc("/platform", routerGetProcPlatformInformation)
-	n.HandleFunc("/process/{pid}/{property}/", routerGetProcProcess)
-	n.HandleFunc("/proto-counter-stat", routerGetProcProtoCountersStat)
-	n.HandleFunc("/proto-pid-stat/{pid}/{protocol}", routerGetProcPidNetStat)
-	n.HandleFunc("/swap-memory", routerGetProcGetSwapMemoryStat)
-	n.HandleFunc("/sys/net/{path}/{link}/{conf}", configureProcSysNet)
-	n.HandleFunc("/sys/vm/{path}", configureProcSysVM)
-	n.HandleFunc("/temperaturestat", routerGetProcTemperatureStat)
-	n.HandleFunc("/userstat", routerGetProcUserStat)
-	n.HandleFunc("/version", routerGetProcVersion)
-	n.HandleFunc("/virtual-memory", routerGetProcVirtualMemoryStat)
-	n.HandleFunc("/virtualization", routerGetProcVirtualization)
+	share.Describe(n.HandleFunc("/avgstat", routerGetProcAvgStat),
+		share.Op("GET", "Load average", nil, load.AvgStat{}))
+	share.Describe(n.HandleFunc("/cpuinfo", routerGetProcCPUInfo),
+		share.Op("GET", "CPU information", nil, []cpu.InfoStat{}))
+	share.Describe(n.HandleFunc("/cputimestat", routerGetProcCPUTimeStat),
+		share.Op("GET", "CPU times per CPU", nil, []cpu.TimesStat{}))
+	share.Describe(n.HandleFunc("/diskusage", routerGetDiskUsage),
+		share.Op("GET", "Disk usage of /", nil, disk.UsageStat{}))
+	share.Describe(n.HandleFunc("/interface-stat", routerGetProcInterfaceStat),
+		share.Op("GET", "Network interfaces", nil, []net.InterfaceStat{}))
+	share.Describe(n.HandleFunc("/iocounters", routerGetIOCounters),
+		share.Op("GET", "Disk IO counters", nil, map[string]disk.IOCountersStat{}))
+	share.Describe(n.HandleFunc("/misc", routerGetProcMisc),
+		share.Op("GET", "Misc devices from /proc/misc", nil, map[int]string{}))
+	share.Describe(n.HandleFunc("/modules", routerGetProcModules),
+		share.Op("GET", "Kernel modules from /proc/modules", nil, []Modules{}))
+	share.Describe(n.HandleFunc("/net/arp", routerGetProcNetArp),
+		share.Op("GET", "ARP table from /proc/net/arp", nil, []NetARP{}))
+	share.Describe(n.HandleFunc("/netdev", routerGetProcNetDev),
+		share.Op("GET", "Network IO counters per interface", nil, []net.IOCountersStat{}))
+	share.Describe(n.HandleFunc("/netstat/{protocol}", routerGetProcNetStat),
+		share.Op("GET", "Connections of a protocol", nil, []net.ConnectionStat{}))
+	share.Describe(n.HandleFunc("/partitions", routerGetPartitions),
+		share.Op("GET", "Disk partitions", nil, []disk.PartitionStat{}))
+	share.Describe(n.HandleFunc("/platform", routerGetProcPlatformInformation),
+		share.Op("GET", "Platform, family and version", nil, map[string]string{}))
+	share.Describe(n.HandleFunc("/process/{pid}/{property}/", routerGetProcProcess),
+		share.Op("GET", "A property of a process", nil, map[string]interface{}{}))
+	share.Describe(n.HandleFunc("/proto-counter-stat", routerGetProcProtoCountersStat),
+		share.Op("GET", "Protocol counters", nil, []net.ProtoCountersStat{}))
+	share.Describe(n.HandleFunc("/proto-pid-stat/{pid}/{protocol}", routerGetProcPidNetStat),
+		share.Op("GET", "Connections of a process", nil, []net.ConnectionStat{}))
+	share.Describe(n.HandleFunc("/swap-memory", routerGetProcGetSwapMemoryStat),
+		share.Op("GET", "Swap memory", nil, mem.SwapMemoryStat{}))
+	share.Describe(n.HandleFunc("/sys/net/{path}/{link}/{conf}", configureProcSysNet),
+		share.Op("GET", "Read a /proc/sys/net value", nil, SysNet{}),
+		share.Op("PUT", "Write a /proc/sys/net value", Info{}, nil).Previewable())
+	share.Describe(n.HandleFunc("/sys/vm/{path}", configureProcSysVM),
+		share.Op("GET", "Read a /proc/sys/vm value", nil, VM{}),
+		share.Op("PUT", "Write a /proc/sys/vm value", Info{}, VM{}).Previewable())
+	share.Describe(n.HandleFunc("/temperaturestat", routerGetProcTemperatureStat),
+		share.Op("GET", "Sensor temperatures", nil, []host.TemperatureStat{}))
+	share.Describe(n.HandleFunc("/userstat", routerGetProcUserStat),
+		share.Op("GET", "Logged in users", nil, []host.UserStat{}))
+	share.Describe(n.HandleFunc("/version", routerGetProcVersion),
+		share.Op("GET", "Host information", nil, host.InfoStat{}))
+	share.Describe(n.HandleFunc("/virtual-memory", routerGetProcVirtualMemoryStat),
+		share.Op("GET", "Virtual memory", nil, mem.VirtualMemoryStat{}))
+	share.Describe(n.HandleFunc("/virtualization", routerGetProcVirtualization),
+		share.Op("GET", "Virtualization system and role", nil, map[string]string{}))
 }
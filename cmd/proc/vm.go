@@ -35,8 +35,12 @@ func (req *VM) GetVM(rw http.ResponseWriter) error {
 }
 
 //SetVM write a value to VM
-func (req *VM) SetVM(rw http.ResponseWriter) error {
-	err := share.WriteOneLineFile(path.Join(vmPath, req.Property), req.Value)
+func (req *VM) SetVM(rw http.ResponseWriter, r *http.Request) error {
+	if p := share.DryRun(r); p != nil {
+		return p.Add(path.Join(vmPath, req.Property), req.Value+"\n")
+	}
+
+	err := share.SetOneLineFile(r, path.Join(vmPath, req.Property), req.Value)
 	if err != nil {
 		return err
 	}
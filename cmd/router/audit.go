@@ -0,0 +1,336 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/coreos/go-systemd/journal"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	auditRedacted    = "<redacted>"
+	auditMaxLineSize = 1024 * 1024
+
+	// larger request bodies are refused rather than buffered for the log
+	auditMaxBodySize = 1 << 20
+)
+
+// request body keys whose values never reach the audit log
+var auditSecretKeys = []string{"password", "passwd", "passphrase", "secret", "token", "private"}
+
+//AuditEvent one mutating API call
+type AuditEvent struct {
+	Time     time.Time   `json:"time"`
+	User     string      `json:"user"`
+	Role     string      `json:"role"`
+	Source   string      `json:"source"`
+	Method   string      `json:"method"`
+	Route    string      `json:"route"`
+	Path     string      `json:"path"`
+	Body     interface{} `json:"body,omitempty"`
+	Status   int         `json:"status"`
+	Duration float64     `json:"duration_ms"`
+}
+
+//AuditFilter query of the audit log
+type AuditFilter struct {
+	User  string
+	Path  string
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+//AuditLog append-only log of AuditEvent
+type AuditLog struct {
+	path    string
+	journal bool
+	file    *os.File
+	lock    sync.Mutex
+}
+
+// statusWriter remembers the status code written by a handler
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("Connection does not support hijacking")
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+
+	return hj.Hijack()
+}
+
+//NewAuditLog opens the audit log for appending
+func NewAuditLog(path string, toJournal bool) (*AuditLog, error) {
+	err := share.CreateDirectoryNested(filepath.Dir(path), 0750)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if toJournal && !journal.Enabled() {
+		log.Warn("Journal is not available, audit events are only written to the audit log file")
+		toJournal = false
+	}
+
+	return &AuditLog{
+		path:    path,
+		journal: toJournal,
+		file:    f,
+	}, nil
+}
+
+func isSecretKey(key string) bool {
+	k := strings.ToLower(key)
+
+	for _, s := range auditSecretKeys {
+		if strings.Contains(k, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redact replaces the values of secret keys in a decoded JSON body
+func redact(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			if isSecretKey(k) {
+				t[k] = auditRedacted
+				continue
+			}
+
+			t[k] = redact(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = redact(e)
+		}
+	}
+
+	return v
+}
+
+func decodeAuditBody(b []byte) interface{} {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+
+	var v interface{}
+	err := json.Unmarshal(b, &v)
+	if err != nil {
+		return fmt.Sprintf("<%d bytes non JSON body>", len(b))
+	}
+
+	return redact(v)
+}
+
+//Record append an event to the audit log and the journal
+func (a *AuditLog) Record(e *AuditEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	_, err = a.file.Write(append(b, '\n'))
+	a.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if a.journal {
+		vars := map[string]string{
+			"API_ROUTERD_AUDIT":  string(b),
+			"API_ROUTERD_USER":   e.User,
+			"API_ROUTERD_METHOD": e.Method,
+			"API_ROUTERD_PATH":   e.Path,
+			"API_ROUTERD_STATUS": strconv.Itoa(e.Status),
+		}
+
+		err = journal.Send(fmt.Sprintf("%s %s from %s by %s: %d", e.Method, e.Path, e.Source, e.User, e.Status), journal.PriInfo, vars)
+		if err != nil {
+			log.Errorf("Failed to send audit event to journal: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func (f *AuditFilter) match(e *AuditEvent) bool {
+	if f.User != "" && e.User != f.User {
+		return false
+	}
+
+	if f.Path != "" && !strings.HasPrefix(e.Path, f.Path) {
+		return false
+	}
+
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+
+	if !f.Until.IsZero() && e.Time.After(f.Until) {
+		return false
+	}
+
+	return true
+}
+
+//Query read the events matching the filter, oldest first
+func (a *AuditLog) Query(filter *AuditFilter) ([]AuditEvent, error) {
+	f, err := os.Open(a.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	events := []AuditEvent{}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), auditMaxLineSize)
+	for scanner.Scan() {
+		var e AuditEvent
+
+		err = json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			log.Errorf("Failed to parse audit event: %s", err)
+			continue
+		}
+
+		if !filter.match(&e) {
+			continue
+		}
+
+		events = append(events, e)
+	}
+
+	if filter.Limit > 0 && len(events) > filter.Limit {
+		events = events[len(events)-filter.Limit:]
+	}
+
+	return events, scanner.Err()
+}
+
+//AuditMiddleware records every request that is not a read
+func (a *AuditLog) AuditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" || r.Method == "HEAD" || r.Method == "OPTIONS" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+
+		var body []byte
+		if r.Body != nil {
+			var err error
+
+			body, err = ioutil.ReadAll(http.MaxBytesReader(w, r.Body, auditMaxBodySize))
+			r.Body.Close()
+			if err != nil {
+				share.HTTPError(w, share.NewError(http.StatusRequestEntityTooLarge, "Request body exceeds %d bytes", auditMaxBodySize))
+				a.record(r, start, nil, http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		a.record(r, start, body, sw.status)
+	})
+}
+
+// record writes the event of a request replied with status
+func (a *AuditLog) record(r *http.Request, start time.Time, body []byte, status int) {
+	e := &AuditEvent{
+		Time:     start.UTC(),
+		Source:   r.RemoteAddr,
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Body:     decodeAuditBody(body),
+		Status:   status,
+		Duration: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+
+	if id := share.RequestIdentity(r); id != nil {
+		e.User = id.User
+		e.Role = id.Role
+	}
+
+	e.Route = routeTemplate(r)
+
+	err := a.Record(e)
+	if err != nil {
+		log.Errorf("Failed to write audit event for %s %s: %s", r.Method, r.URL.Path, err)
+	}
+}
+
+//Denied records a request the auth middleware refused, reads included.
+//user is nil when the caller could not be identified
+func (a *AuditLog) Denied(r *http.Request, user *AuthUser, status int) {
+	if user != nil {
+		r = share.WithIdentity(r, &share.Identity{User: user.Name, Role: user.Role.Name})
+	}
+
+	a.record(r, time.Now(), nil, status)
+}
@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+func parseAuditFilter(r *http.Request) (*AuditFilter, error) {
+	q := r.URL.Query()
+
+	f := &AuditFilter{
+		User: q.Get("user"),
+		Path: q.Get("path"),
+	}
+
+	var err error
+
+	if s := q.Get("since"); s != "" {
+		f.Since, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse since '%s': %s", s, err)
+		}
+	}
+
+	if s := q.Get("until"); s != "" {
+		f.Until, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse until '%s': %s", s, err)
+		}
+	}
+
+	if s := q.Get("limit"); s != "" {
+		f.Limit, err = strconv.Atoi(s)
+		if err != nil || f.Limit < 0 {
+			return nil, fmt.Errorf("Failed to parse limit '%s'", s)
+		}
+	}
+
+	return f, nil
+}
+
+func (a *AuditLog) routerAudit(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		f, err := parseAuditFilter(r)
+		if err != nil {
+			share.HTTPError(rw, share.BadRequest(err))
+			return
+		}
+
+		events, err := a.Query(f)
+		if err != nil {
+			log.Errorf("Failed to query audit log %s: %s", a.path, err)
+			share.HTTPError(rw, err)
+			return
+		}
+
+		share.JSONResponse(events, rw)
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+// registerRouterAudit register the audit query endpoint with mux
+func registerRouterAudit(router *mux.Router, a *AuditLog) {
+	share.Describe(router.HandleFunc("/audit", a.routerAudit),
+		share.Op("GET", "Query the audit log by user, path, since, until and limit", nil, []AuditEvent{}))
+}
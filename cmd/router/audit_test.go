@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestAuditLog(t *testing.T) *AuditLog {
+	a, err := NewAuditLog(filepath.Join(t.TempDir(), "audit.log"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a
+}
+
+func TestAuditDenied(t *testing.T) {
+	a := newTestAuditLog(t)
+
+	db := newTestTokenDB(t, "joy joysecret readonly")
+	db.OnDenied(a.Denied)
+
+	h := db.AuthMiddleware(a.AuditMiddleware(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {})))
+
+	for _, tt := range []struct {
+		token  string
+		method string
+	}{
+		{"wrong", "GET"},
+		{"joysecret", "POST"},
+		{"joysecret", "GET"},
+	} {
+		r := httptest.NewRequest(tt.method, "/api/network/link/add", nil)
+		r.Header.Set("X-Session-Token", tt.token)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	events, err := a.Query(&AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the allowed read is not audited
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+
+	if events[0].Status != http.StatusForbidden || events[0].User != "" || events[0].Method != "GET" {
+		t.Errorf("unknown token event %+v", events[0])
+	}
+
+	if events[1].Status != http.StatusForbidden || events[1].User != "joy" || events[1].Role != RoleReadOnly {
+		t.Errorf("missing permission event %+v", events[1])
+	}
+}
+
+func TestAuditBodyLimit(t *testing.T) {
+	a := newTestAuditLog(t)
+
+	called := false
+	h := a.AuditMiddleware(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	body := bytes.Repeat([]byte("x"), auditMaxBodySize+1)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/api/network/link/add", bytes.NewReader(body)))
+
+	if w.Code != http.StatusRequestEntityTooLarge || called {
+		t.Errorf("oversized body replied %d, handler called %v", w.Code, called)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/api/network/link/add", strings.NewReader(`{"link":"eth0","secret":"x"}`)))
+
+	if w.Code != http.StatusOK || !called {
+		t.Errorf("small body replied %d, handler called %v", w.Code, called)
+	}
+
+	events, err := a.Query(&AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 || events[0].Status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("events %+v", events)
+	}
+
+	if b, ok := events[1].Body.(map[string]interface{}); !ok || b["secret"] != auditRedacted {
+		t.Errorf("body not redacted: %+v", events[1].Body)
+	}
+}
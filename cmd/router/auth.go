@@ -3,54 +3,437 @@
 package router
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 
+	"github.com/RestGW/api-routerd/cmd/conf"
 	"github.com/RestGW/api-routerd/cmd/share"
 
+	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
-	authConfPath = "/etc/api-routerd/api-routerd-auth.conf"
+	authCertConfPath = "/etc/api-routerd/api-routerd-certs.conf"
 )
 
+// routes served without authentication, for load balancer probes
+var publicRoutes = []string{"/healthz"}
+
+func isPublicRoute(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+
+	t, _ := route.GetPathTemplate()
+	for _, p := range publicRoutes {
+		if t == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+//AuthUser user and role from the auth config
+type AuthUser struct {
+	Name string
+	Role *Role
+}
+
+// credential one line of the auth config. Either hash (bcrypt)
+// or digest (sha256 of a plaintext secret) is set.
+type credential struct {
+	user   *AuthUser
+	hash   []byte
+	digest []byte
+}
+
 //TokenDB token DB
 type TokenDB struct {
-	tokenUsers map[string]string
+	credentials []*credential
+	store       *TokenStore
+
+	// auth file and mode from [Auth]
+	file string
+	mode string
+
+	// client certificate CN or SAN to user
+	certUsers map[string]*AuthUser
+
+	// Unix socket peer uid or gid to user
+	peerUIDs map[uint32]*AuthUser
+	peerGIDs map[uint32]*AuthUser
+
+	// tokens already verified against a bcrypt hash, keyed by sha256 of the token
+	verified map[[sha256.Size]byte]*AuthUser
+	lock     sync.RWMutex
+
+	// held for writing while a reload swaps the tables above
+	reloadLock sync.RWMutex
+
+	// told about every refused request, for the audit log
+	denied func(r *http.Request, user *AuthUser, status int)
+}
+
+//OnDenied calls fn for every request the middleware refuses
+func (db *TokenDB) OnDenied(fn func(r *http.Request, user *AuthUser, status int)) {
+	db.denied = fn
+}
+
+func isHashedSecret(secret string) bool {
+	return strings.HasPrefix(secret, "$2a$") || strings.HasPrefix(secret, "$2b$") || strings.HasPrefix(secret, "$2y$")
+}
+
+//HashSecret generates a salted bcrypt hash of a secret for the auth config
+func HashSecret(secret string) (string, error) {
+	if secret == "" {
+		return "", errors.New("Empty secret")
+	}
+
+	h, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(h), nil
+}
+
+// lookup finds the user of a token. A bare token is only compared with the
+// plaintext secrets, bcrypt hashes need "<user>:<secret>" so a request costs
+// at most one bcrypt comparison whatever the number of users
+func (db *TokenDB) lookup(token string) (*AuthUser, bool) {
+	if token == "" {
+		return nil, false
+	}
+
+	digest := sha256.Sum256([]byte(token))
+
+	db.lock.RLock()
+	user, found := db.verified[digest]
+	db.lock.RUnlock()
+	if found {
+		return user, true
+	}
+
+	// Walk all plaintext entries so the time taken does not depend on which one matched
+	for _, c := range db.credentials {
+		if c.digest != nil && subtle.ConstantTimeCompare(c.digest, digest[:]) == 1 {
+			user = c.user
+		}
+	}
+
+	if user != nil {
+		return user, true
+	}
+
+	name := strings.SplitN(token, ":", 2)
+	if len(name) != 2 {
+		return nil, false
+	}
+
+	for _, c := range db.credentials {
+		if c.hash == nil || c.user.Name != name[0] {
+			continue
+		}
+
+		if bcrypt.CompareHashAndPassword(c.hash, []byte(name[1])) == nil {
+			db.lock.Lock()
+			db.verified[digest] = c.user
+			db.lock.Unlock()
+
+			return c.user, true
+		}
+
+		break
+	}
+
+	return nil, false
+}
+
+// certificateIdentities CN and SANs of a verified client certificate
+func certificateIdentities(cert *x509.Certificate) []string {
+	ids := []string{cert.Subject.CommonName}
+
+	ids = append(ids, cert.DNSNames...)
+	ids = append(ids, cert.EmailAddresses...)
+
+	for _, ip := range cert.IPAddresses {
+		ids = append(ids, ip.String())
+	}
+
+	for _, uri := range cert.URIs {
+		ids = append(ids, uri.String())
+	}
+
+	return ids
+}
+
+func (db *TokenDB) lookupCertificate(r *http.Request) (*AuthUser, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+
+	for _, id := range certificateIdentities(r.TLS.VerifiedChains[0][0]) {
+		if user, found := db.certUsers[id]; found && id != "" {
+			return user, true
+		}
+	}
+
+	log.Infof("No user mapped to client certificate '%s'", r.TLS.VerifiedChains[0][0].Subject.CommonName)
+
+	return nil, false
+}
+
+// authenticate identifies the caller by peer credentials, client certificate or session token
+func (db *TokenDB) authenticate(r *http.Request) (*AuthUser, bool) {
+	db.reloadLock.RLock()
+	defer db.reloadLock.RUnlock()
+
+	if db.mode != conf.AuthModeToken {
+		user, found := db.lookupPeer(r)
+		if found {
+			return user, true
+		}
+
+		user, found = db.lookupCertificate(r)
+		if found {
+			return user, true
+		}
+	}
+
+	if db.mode == conf.AuthModeCertificate {
+		return nil, false
+	}
+
+	token := r.Header.Get("X-Session-Token")
+
+	// scrapers such as Prometheus only know bearer tokens
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+
+	user, found := db.lookup(token)
+	if !found && db.store != nil {
+		user, found = db.store.Lookup(token, r.RemoteAddr)
+	}
+
+	return user, found
 }
 
 //AuthMiddleware Authenticate the User
 func (db *TokenDB) AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
-		token := r.Header.Get("X-Session-Token")
-
-		if user, found := db.tokenUsers[token]; found {
-			log.Printf("Authenticated user %s\n", user)
+		if isPublicRoute(r) {
 			next.ServeHTTP(w, r)
-		} else {
-			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		user, found := db.authenticate(r)
+		if !found {
+			share.HTTPError(w, share.NewError(http.StatusForbidden, "Forbidden"))
 			log.Infof("Unauthorized user")
+			if db.denied != nil {
+				db.denied(r, nil, http.StatusForbidden)
+			}
+			return
 		}
+
+		permission := requiredPermission(r)
+		if !user.Role.Allowed(permission) {
+			share.HTTPError(w, share.NewError(http.StatusForbidden, "Forbidden: role '%s' lacks permission '%s'", user.Role.Name, permission))
+			log.Infof("User %s with role %s denied permission %s on %s %s", user.Name, user.Role.Name, permission, r.Method, r.URL.Path)
+			if db.denied != nil {
+				db.denied(r, user, http.StatusForbidden)
+			}
+			return
+		}
+
+		log.Printf("Authenticated user %s\n", user.Name)
+		next.ServeHTTP(w, share.WithIdentity(r, &share.Identity{User: user.Name, Role: user.Role.Name}))
 	})
 }
 
-//InitAuthMiddleware init middleware
-func InitAuthMiddleware() (TokenDB, error) {
-	db := TokenDB{make(map[string]string)}
+// parseUserRole parses "<user> [role]" fields
+func parseUserRole(fields []string) (*AuthUser, error) {
+	roleName := defaultRole
+	if len(fields) > 1 {
+		roleName = fields[1]
+	} else {
+		log.Warnf("User %s has no role, granting '%s'", fields[0], defaultRole)
+	}
 
-	lines, r := share.ReadFullFile(authConfPath)
-	if r != nil {
-		log.Fatal("Failed to read auth config file")
-		return db, errors.New("Failed to read auth config file")
+	role, err := LookupRole(roleName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthUser{
+		Name: fields[0],
+		Role: role,
+	}, nil
+}
+
+// initCertUsers read the client certificate to user mapping
+func (db *TokenDB) initCertUsers(strict bool) error {
+	if !share.PathExists(authCertConfPath) {
+		return nil
+	}
+
+	lines, err := share.ReadFullFile(authCertConfPath)
+	if err != nil {
+		return err
+	}
+
+	// <CN or SAN> <user> [role]
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			log.Errorf("Failed to parse client certificate config line: %s", line)
+			if strict {
+				return fmt.Errorf("Failed to parse client certificate config line: %s", line)
+			}
+			continue
+		}
+
+		user, err := parseUserRole(fields[1:])
+		if err != nil {
+			log.Errorf("Failed to assign role to certificate %s: %s", fields[0], err)
+			if strict {
+				return err
+			}
+			continue
+		}
+
+		db.certUsers[fields[0]] = user
+	}
+
+	return nil
+}
+
+func newTokenDB(store *TokenStore) *TokenDB {
+	return &TokenDB{
+		store:     store,
+		file:      conf.Current().Auth.File,
+		mode:      conf.Current().Auth.Mode,
+		certUsers: make(map[string]*AuthUser),
+		peerUIDs:  make(map[uint32]*AuthUser),
+		peerGIDs:  make(map[uint32]*AuthUser),
+		verified:  make(map[[sha256.Size]byte]*AuthUser),
+	}
+}
+
+// initUsers read the auth config. With strict set any invalid line fails the whole file
+func (db *TokenDB) initUsers(strict bool) error {
+	lines, err := share.ReadFullFile(db.file)
+	if err != nil {
+		return errors.New("Failed to read auth config file")
 	}
 
+	// <user> <secret|bcrypt hash> [role]
 	for _, line := range lines {
 		authLine := strings.Fields(line)
-		db.tokenUsers[authLine[1]] = authLine[0]
+		if len(authLine) < 2 {
+			log.Errorf("Failed to parse auth config line: %s", line)
+			if strict {
+				return fmt.Errorf("Failed to parse auth config line: %s", line)
+			}
+			continue
+		}
+
+		user, err := parseUserRole(append(authLine[:1:1], authLine[2:]...))
+		if err != nil {
+			log.Errorf("Failed to assign role to user %s: %s", authLine[0], err)
+			if strict {
+				return err
+			}
+			continue
+		}
+
+		c := &credential{
+			user: user,
+		}
+
+		if isHashedSecret(authLine[1]) {
+			c.hash = []byte(authLine[1])
+		} else {
+			log.Warnf("User %s has a plaintext secret in %s. Use 'api-routerd hash-secret' to hash it", authLine[0], db.file)
+
+			d := sha256.Sum256([]byte(authLine[1]))
+			c.digest = d[:]
+		}
+
+		db.credentials = append(db.credentials, c)
+	}
+
+	return nil
+}
+
+//InitAuthMiddleware init middleware
+func InitAuthMiddleware(store *TokenStore) (*TokenDB, error) {
+	db := newTokenDB(store)
+
+	err := db.initCertUsers(false)
+	if err != nil {
+		log.Errorf("Failed to read client certificate config file %s: %s", authCertConfPath, err)
+	}
+
+	err = db.initPeerUsers(false)
+	if err != nil {
+		log.Errorf("Failed to read peer config file %s: %s", authPeerConfPath, err)
+	}
+
+	err = db.initUsers(false)
+	if err != nil {
+		log.Fatal("Failed to read auth config file")
+		return db, err
 	}
 
 	return db, nil
 }
+
+//Reload re-reads the auth, client certificate and peer configs.
+//Any invalid entry rejects the reload and the current users stay active.
+func (db *TokenDB) Reload() error {
+	n := newTokenDB(db.store)
+
+	err := n.initCertUsers(true)
+	if err != nil {
+		return fmt.Errorf("%s: %s", authCertConfPath, err)
+	}
+
+	err = n.initPeerUsers(true)
+	if err != nil {
+		return fmt.Errorf("%s: %s", authPeerConfPath, err)
+	}
+
+	err = n.initUsers(true)
+	if err != nil {
+		return fmt.Errorf("%s: %s", n.file, err)
+	}
+
+	db.reloadLock.Lock()
+	defer db.reloadLock.Unlock()
+
+	db.file = n.file
+	db.mode = n.mode
+	db.credentials = n.credentials
+	db.certUsers = n.certUsers
+	db.peerUIDs = n.peerUIDs
+	db.peerGIDs = n.peerGIDs
+
+	// cached bcrypt results may belong to removed users
+	db.lock.Lock()
+	db.verified = n.verified
+	db.lock.Unlock()
+
+	return nil
+}
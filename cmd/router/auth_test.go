@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashSecret(t *testing.T) {
+	h, err := HashSecret("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !isHashedSecret(h) {
+		t.Errorf("'%s' is not recognized as a bcrypt hash", h)
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(h), []byte("s3cret")) != nil {
+		t.Error("hash does not match its secret")
+	}
+
+	_, err = HashSecret("")
+	if err == nil {
+		t.Error("empty secret was hashed")
+	}
+}
+
+func TestLookupPlaintext(t *testing.T) {
+	db := newTestTokenDB(t, "joy plain readonly", "max other admin")
+
+	user, found := db.lookup("plain")
+	if !found || user.Name != "joy" {
+		t.Errorf("plaintext secret found %v user %v", found, user)
+	}
+
+	user, found = db.lookup("joy:plain")
+	if found {
+		t.Errorf("user:secret matched plaintext entry of %s", user.Name)
+	}
+
+	for _, token := range []string{"", "plai", "plainx", "PLAIN"} {
+		if _, found := db.lookup(token); found {
+			t.Errorf("token '%s' was accepted", token)
+		}
+	}
+}
+
+func TestLookupBcrypt(t *testing.T) {
+	h, err := HashSecret("s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := newTestTokenDB(t, "susant "+h+" admin", "joy plain readonly")
+
+	user, found := db.lookup("susant:s3cret")
+	if !found || user.Name != "susant" || user.Role.Name != RoleAdmin {
+		t.Fatalf("bcrypt secret found %v user %v", found, user)
+	}
+
+	// served from the cache of verified tokens the second time
+	if len(db.verified) != 1 {
+		t.Errorf("verified cache has %d entries, want 1", len(db.verified))
+	}
+
+	user, found = db.lookup("susant:s3cret")
+	if !found || user.Name != "susant" {
+		t.Errorf("cached bcrypt secret found %v user %v", found, user)
+	}
+
+	// a bare token never reaches bcrypt
+	for _, token := range []string{"s3cret", "joy:s3cret", "susant:wrong", "nobody:s3cret", h} {
+		if _, found := db.lookup(token); found {
+			t.Errorf("token '%s' was accepted", token)
+		}
+	}
+
+	if len(db.verified) != 1 {
+		t.Errorf("failed lookups were cached, %d entries", len(db.verified))
+	}
+}
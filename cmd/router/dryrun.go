@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"net/http"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+)
+
+func isMutating(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return false
+	}
+
+	return true
+}
+
+func previewable(r *http.Request) bool {
+	op := share.RequestOperation(r)
+
+	return op != nil && op.DryRun
+}
+
+// dryRunMiddleware runs ?dry_run=true requests of previewable operations with
+// a preview in their context and replies the files they would have written.
+// Other mutating operations are refused so a dry run never changes anything
+func dryRunMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dry, err := share.ParseDryRun(r)
+		if err != nil {
+			share.HTTPError(w, err)
+			return
+		}
+
+		if !dry || !isMutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !previewable(r) {
+			share.HTTPError(w, share.NewError(http.StatusNotImplemented, "Dry run not supported by %s %s", r.Method, r.URL.Path))
+			return
+		}
+
+		p := &share.Preview{DryRun: true, Files: []share.FileChange{}}
+		b := share.NewResponseBuffer()
+
+		next.ServeHTTP(b, share.WithDryRun(r, p))
+
+		if b.Failed() {
+			b.Replay(w)
+			return
+		}
+
+		share.JSONResponse(p, w)
+	})
+}
@@ -0,0 +1,351 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/RestGW/api-routerd/cmd/conf"
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/coreos/go-systemd/activation"
+	log "github.com/sirupsen/logrus"
+)
+
+// listener a socket served by api-routerd
+type listener struct {
+	net.Listener
+	tls bool
+
+	// set when a reload retires the listener
+	closed bool
+}
+
+// listenSpec a socket api-routerd opens itself
+type listenSpec struct {
+	network string
+	address string
+	tls     bool
+}
+
+func (spec *listenSpec) key() string {
+	return spec.network + "://" + spec.address
+}
+
+func (spec *listenSpec) open() (*listener, error) {
+	var l net.Listener
+	var err error
+
+	if spec.network == "unix" {
+		l, err = listenUnix(spec.address)
+	} else {
+		l, err = net.Listen(spec.network, spec.address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to listen on %s: %s", spec.key(), err)
+	}
+
+	return &listener{Listener: l, tls: spec.tls}, nil
+}
+
+// activatedListeners the sockets passed by systemd. The TLS mode is taken from the
+// [[Network.Listen]] entry with the same FileDescriptorName
+func activatedListeners(listen []conf.Listen, tlsConfig *tls.Config) ([]*listener, error) {
+	var listeners []*listener
+
+	activated, err := activation.ListenersWithNames()
+	if err != nil {
+		log.Infof("Failed to retrieve listeners: %s", err)
+	}
+
+	for name, ls := range activated {
+		useTLS := tlsConfig != nil
+
+		for _, c := range listen {
+			if c.Name == name {
+				useTLS = c.TLS
+			}
+		}
+
+		if useTLS && tlsConfig == nil {
+			return nil, fmt.Errorf("TLS requested on socket %s but no server certificate is configured", name)
+		}
+
+		for _, l := range ls {
+			if l == nil {
+				continue
+			}
+
+			listeners = append(listeners, &listener{Listener: l, tls: useTLS})
+		}
+	}
+
+	return listeners, nil
+}
+
+// listenSpecs the configured, default TCP and Unix socket listeners
+func listenSpecs(ip string, port string, unixSocket string, listen []conf.Listen, activated int, tlsConfig *tls.Config) ([]listenSpec, error) {
+	var specs []listenSpec
+
+	for _, c := range listen {
+		if c.Address == "" {
+			continue
+		}
+
+		specs = append(specs, listenSpec{network: "tcp", address: c.Address, tls: c.TLS})
+	}
+
+	if len(specs) <= 0 && activated <= 0 {
+		specs = append(specs, listenSpec{network: "tcp", address: net.JoinHostPort(ip, port), tls: tlsConfig != nil})
+	}
+
+	for _, spec := range specs {
+		if spec.tls && tlsConfig == nil {
+			return nil, fmt.Errorf("TLS requested on %s but no server certificate is configured", spec.address)
+		}
+	}
+
+	if unixSocket != "" {
+		specs = append(specs, listenSpec{network: "unix", address: unixSocket})
+	}
+
+	return specs, nil
+}
+
+// server serves all listeners of one http.Server. Listeners can be swapped
+// on reload without dropping requests in flight on the retired ones.
+type server struct {
+	srv       *http.Server
+	tlsConfig *tls.Config
+
+	// socket activated listeners stay for the lifetime of the process
+	activated []*listener
+	listeners map[string]*listener
+
+	errs chan error
+	lock sync.Mutex
+}
+
+func newServer(srv *http.Server, tlsConfig *tls.Config, activated []*listener) *server {
+	return &server{
+		srv:       srv,
+		tlsConfig: tlsConfig,
+		activated: activated,
+		listeners: make(map[string]*listener),
+		errs:      make(chan error, 1),
+	}
+}
+
+func (s *server) serveListener(l *listener) {
+	var ln net.Listener = l.Listener
+
+	if l.tls {
+		ln = tls.NewListener(l.Listener, s.tlsConfig)
+	}
+
+	log.Infof("Listening on %s://%s (TLS: %t)", l.Addr().Network(), l.Addr().String(), l.tls)
+
+	go func() {
+		err := s.srv.Serve(ln)
+
+		s.lock.Lock()
+		closed := l.closed
+		s.lock.Unlock()
+
+		if closed {
+			return
+		}
+
+		select {
+		case s.errs <- err:
+		default:
+		}
+	}()
+}
+
+// apply opens the listeners that are new in specs and retires the ones no longer in it.
+// If a new socket fails to open the current listeners are left untouched.
+func (s *server) apply(specs []listenSpec) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	wanted := make(map[string]listenSpec)
+	for _, spec := range specs {
+		wanted[spec.key()] = spec
+	}
+
+	opened := make(map[string]*listener)
+	for k, spec := range wanted {
+		if _, ok := s.listeners[k]; ok {
+			continue
+		}
+
+		l, err := spec.open()
+		if err != nil {
+			closeListeners(opened)
+			return err
+		}
+
+		opened[k] = l
+	}
+
+	for k, l := range s.listeners {
+		spec, ok := wanted[k]
+		if ok && spec.tls == l.tls {
+			continue
+		}
+
+		// stop accepting. Connections already accepted are served to the end
+		l.closed = true
+		l.Close()
+		delete(s.listeners, k)
+
+		log.Infof("Stopped listening on %s", k)
+
+		if !ok {
+			continue
+		}
+
+		// TLS mode changed on the same address
+		nl, err := spec.open()
+		if err != nil {
+			log.Errorf("Failed to reopen %s: %s", k, err)
+			continue
+		}
+
+		opened[k] = nl
+	}
+
+	for k, l := range opened {
+		s.listeners[k] = l
+		s.serveListener(l)
+	}
+
+	return nil
+}
+
+// start serves the activated listeners and opens the ones in specs
+func (s *server) start(specs []listenSpec) error {
+	err := s.apply(specs)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, l := range s.activated {
+		s.serveListener(l)
+	}
+
+	return nil
+}
+
+// wait returns the first fatal error of a listener or nil once the server is shut down
+func (s *server) wait() error {
+	err := <-s.errs
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
+
+func closeListeners(listeners map[string]*listener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
+
+// loadClientCA reads the CA bundle used to verify client certificates
+func loadClientCA(path string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("No certificates found in %s", path)
+	}
+
+	return pool, nil
+}
+
+// tlsVersions MinVersion in [TLS]
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func newTLSConfig(c *conf.TLSConf) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	minVersion, ok := tlsVersions[c.MinVersion]
+	if !ok {
+		minVersion = tls.VersionTLS12
+	}
+
+	cfg := &tls.Config{
+		Certificates:             []tls.Certificate{cert},
+		MinVersion:               minVersion,
+		CurvePreferences:         []tls.CurveID{tls.CurveP521, tls.CurveP384, tls.CurveP256},
+		PreferServerCipherSuites: false,
+	}
+
+	if share.PathExists(c.ClientCA) {
+		pool, err := loadClientCA(c.ClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to load client CA bundle %s: %s", c.ClientCA, err)
+		}
+
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+
+		log.Info("Client certificate authentication enabled")
+	}
+
+	return cfg, nil
+}
+
+// listenUnix creates the local Unix socket listener
+func listenUnix(path string) (net.Listener, error) {
+	err := share.CreateDirectoryNested(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	// stale socket from a previous run
+	if share.PathExists(path) {
+		err = os.Remove(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chmod(path, 0660)
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/metrics"
+)
+
+// metricsMiddleware counts the requests per route template so paths with
+// variables do not create a series per value
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		metrics.ObserveRequest(routeTemplate(r), r.Method, sw.status, time.Since(start))
+	})
+}
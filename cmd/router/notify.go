@@ -0,0 +1,187 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/metrics"
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/coreos/go-systemd/daemon"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// how often STATUS= is refreshed
+	statusInterval = 30 * time.Second
+
+	// each liveness probe must answer within
+	probeTimeout = 5 * time.Second
+)
+
+// notify sends state to the service manager. Without $NOTIFY_SOCKET it
+// does nothing, for example when not started by systemd
+func notify(state string) {
+	_, err := daemon.SdNotify(false, state)
+	if err != nil {
+		log.Errorf("Failed to notify systemd '%s': %s", state, err)
+	}
+}
+
+// probeAddress where the probe connects to reach l
+func probeAddress(l *listener) (string, string) {
+	addr := l.Addr()
+	if addr.Network() != "tcp" {
+		return addr.Network(), addr.String()
+	}
+
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.Network(), addr.String()
+	}
+
+	// a wildcard bind is reached over loopback, whichever family is up
+	ip := net.ParseIP(host)
+	if ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+
+	return "tcp", net.JoinHostPort(host, port)
+}
+
+// probeHTTP checks the server still accepts and answers on one of its
+// listeners. "OPTIONS *" is answered by net/http before any handler so it
+// needs no credentials
+func (s *server) probeHTTP() error {
+	l := s.probeListener()
+	if l == nil {
+		return nil
+	}
+
+	network, address := probeAddress(l)
+
+	conn, err := net.DialTimeout(network, address, probeTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if l.tls {
+		conn = tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+	}
+
+	conn.SetDeadline(time.Now().Add(probeTimeout))
+
+	_, err = fmt.Fprintf(conn, "OPTIONS * HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+	if err != nil {
+		return err
+	}
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(line, "HTTP/1.1 200") {
+		return fmt.Errorf("Unexpected reply '%s' on %s", strings.TrimSpace(line), address)
+	}
+
+	return nil
+}
+
+// probeListener prefers a listener the probe can use without TLS. TLS
+// listeners that require client certificates cannot be probed
+func (s *server) probeListener() *listener {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var candidate *listener
+
+	all := append([]*listener{}, s.activated...)
+	for _, l := range s.listeners {
+		all = append(all, l)
+	}
+
+	for _, l := range all {
+		if !l.tls {
+			return l
+		}
+
+		if s.tlsConfig != nil && s.tlsConfig.ClientAuth != tls.RequireAnyClientCert &&
+			s.tlsConfig.ClientAuth != tls.RequireAndVerifyClientCert {
+			candidate = l
+		}
+	}
+
+	return candidate
+}
+
+// alive whether the watchdog may be pinged
+func (s *server) alive() error {
+	err := share.PingSystemBus("", probeTimeout)
+	if err != nil {
+		return fmt.Errorf("D-Bus: %s", err)
+	}
+
+	err = s.probeHTTP()
+	if err != nil {
+		return fmt.Errorf("HTTP: %s", err)
+	}
+
+	return nil
+}
+
+func (s *server) status() string {
+	s.lock.Lock()
+	n := len(s.activated) + len(s.listeners)
+	s.lock.Unlock()
+
+	total, failed := metrics.Requests()
+
+	return fmt.Sprintf("STATUS=Listening on %d sockets, served %d requests (%d failed)", n, total, failed)
+}
+
+// supervise reports status to systemd and pings the watchdog as long as
+// the liveness checks pass
+func (s *server) supervise(stop <-chan struct{}) {
+	status := time.NewTicker(statusInterval)
+	defer status.Stop()
+
+	var ping <-chan time.Time
+
+	interval, err := daemon.SdWatchdogEnabled(false)
+	if err != nil {
+		log.Errorf("Ignoring systemd watchdog: %s", err)
+	}
+
+	if interval > 0 {
+		t := time.NewTicker(interval / 2)
+		defer t.Stop()
+
+		ping = t.C
+		log.Infof("Pinging systemd watchdog every %s", interval/2)
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-status.C:
+			notify(s.status())
+		case <-ping:
+			err := s.alive()
+			if err != nil {
+				log.Errorf("Liveness check failed, not pinging the watchdog: %s", err)
+				continue
+			}
+
+			notify(daemon.SdNotifyWatchdog)
+		}
+	}
+}
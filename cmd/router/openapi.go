@@ -0,0 +1,319 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/conf"
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	openAPIVersion = "3.0.3"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	// {name} or {name:pattern} in a mux path template
+	pathVarRegexp = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+)
+
+type object map[string]interface{}
+
+// schemas collects the named structs referenced from the paths into components
+type schemas struct {
+	defs  map[string]object
+	types map[string]reflect.Type
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		defs:  make(map[string]object),
+		types: make(map[string]reflect.Type),
+	}
+}
+
+// name a component name for t, qualified by its parent package when two
+// packages share a name such as netlink/link and networkd/link
+func (s *schemas) name(t reflect.Type) string {
+	name := t.String()
+	if u, ok := s.types[name]; !ok || u == t {
+		return name
+	}
+
+	return path.Base(path.Dir(t.PkgPath())) + "." + name
+}
+
+func schemaRef(name string) object {
+	return object{"$ref": "#/components/schemas/" + name}
+}
+
+func implements(t reflect.Type, i reflect.Type) bool {
+	return t.Implements(i) || reflect.PtrTo(t).Implements(i)
+}
+
+// schema the JSON schema of the way encoding/json marshals t
+func (s *schemas) schema(t reflect.Type) object {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return object{"type": "string", "format": "date-time"}
+	case implements(t, jsonMarshalerType):
+		return object{}
+	case implements(t, textMarshalerType):
+		return object{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return object{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return object{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return object{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return object{"type": "number"}
+	case reflect.String:
+		return object{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return object{"type": "string", "format": "byte"}
+		}
+
+		return object{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return object{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+
+		name := s.name(t)
+		if _, ok := s.defs[name]; !ok {
+			// placeholder first so recursive types terminate
+			s.types[name] = t
+			s.defs[name] = object{}
+			s.defs[name] = s.structSchema(t)
+		}
+
+		return schemaRef(name)
+	}
+
+	// interfaces, funcs and channels
+	return object{}
+}
+
+func (s *schemas) structSchema(t reflect.Type) object {
+	properties := object{}
+	s.addFields(t, properties)
+
+	return object{"type": "object", "properties": properties}
+}
+
+func (s *schemas) addFields(t reflect.Type, properties object) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		// untagged embedded structs are flattened like encoding/json does
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			s.addFields(ft, properties)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		properties[name] = s.schema(f.Type)
+	}
+}
+
+func (s *schemas) content(v interface{}) object {
+	return object{
+		"application/json": object{
+			"schema": s.schema(reflect.TypeOf(v)),
+		},
+	}
+}
+
+func (s *schemas) operation(op share.Operation, deprecated bool) object {
+	responses := object{
+		"default": object{
+			"description": "Error",
+			"content":     s.content(share.Error{}),
+		},
+	}
+
+	if op.Response != nil {
+		responses["200"] = object{
+			"description": "OK",
+			"content":     s.content(op.Response),
+		}
+	} else {
+		responses["200"] = object{"description": "OK"}
+	}
+
+	o := object{
+		"summary":   op.Summary,
+		"responses": responses,
+	}
+
+	if deprecated {
+		o["deprecated"] = true
+	}
+
+	// only the calls with a preview support dry_run=true, the other mutating
+	// calls refuse it
+	if op.DryRun {
+		o["x-dry-run"] = true
+	} else if isMutating(op.Method) {
+		responses["501"] = object{"description": "dry_run=true is not supported, nothing was changed"}
+	}
+
+	var params []object
+
+	if op.DryRun {
+		params = append(params, object{
+			"name":        "dry_run",
+			"in":          "query",
+			"description": "Reply the files the request would write and their diff without changing anything",
+			"schema":      object{"type": "boolean"},
+		})
+	}
+
+	if op.Confirm {
+		params = append(params, object{
+			"name":        "confirm_timeout",
+			"in":          "query",
+			"description": "Roll the change back unless /network/confirm/{id} is called within this duration, such as 120s",
+			"schema":      object{"type": "string"},
+		})
+	}
+
+	if len(params) > 0 {
+		o["parameters"] = params
+	}
+
+	if op.Request != nil {
+		o["requestBody"] = object{
+			"required": true,
+			"content":  s.content(op.Request),
+		}
+	}
+
+	return o
+}
+
+func pathParameters(template string) []object {
+	var params []object
+
+	for _, m := range pathVarRegexp.FindAllStringSubmatch(template, -1) {
+		params = append(params, object{
+			"name":     m[1],
+			"in":       "path",
+			"required": true,
+			"schema":   object{"type": "string"},
+		})
+	}
+
+	return params
+}
+
+// openAPIDocument walks the router of a mount and builds the OpenAPI document
+// from the operations every Register* function declared with share.Describe
+func openAPIDocument(r *mux.Router, m *apiMount) (object, error) {
+	s := newSchemas()
+	paths := object{}
+
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		var ops []share.Operation
+		for _, op := range share.RouteOperations(route) {
+			if op.ServedIn(m.version) {
+				ops = append(ops, op)
+			}
+		}
+
+		if len(ops) == 0 {
+			return nil
+		}
+
+		template, err := mountTemplate(route, m)
+		if err != nil {
+			return err
+		}
+
+		p := pathVarRegexp.ReplaceAllString(template, "{$1}")
+
+		item, ok := paths[p].(object)
+		if !ok {
+			item = object{}
+
+			params := pathParameters(template)
+			if len(params) > 0 {
+				item["parameters"] = params
+			}
+
+			paths[p] = item
+		}
+
+		for _, op := range ops {
+			item[strings.ToLower(op.Method)] = s.operation(op, m.deprecated)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return object{
+		"openapi": openAPIVersion,
+		"info": object{
+			"title":   "api-routerd " + m.version,
+			"version": conf.Version,
+		},
+		"paths": paths,
+		"components": object{
+			"schemas": s.defs,
+			"securitySchemes": object{
+				"token": object{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "X-Session-Token",
+				},
+			},
+		},
+		"security": []object{{"token": []string{}}},
+	}, nil
+}
@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"net/http"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+)
+
+// openAPI serves the document of the routes of the mount a request is sent to
+type openAPI struct {
+	root *mux.Router
+}
+
+func (o *openAPI) routerOpenAPI(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		doc, err := openAPIDocument(o.root, requestMount(r))
+		if err != nil {
+			log.Errorf("Failed to build OpenAPI document: %s", err)
+			share.HTTPError(rw, err)
+			return
+		}
+
+		share.JSONResponse(doc, rw)
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+// registerRouterOpenAPI register the OpenAPI document of each mount with mux
+func registerRouterOpenAPI(router *mux.Router) {
+	o := &openAPI{root: router}
+
+	share.Describe(router.HandleFunc("/openapi.json", o.routerOpenAPI),
+		share.Op("GET", "OpenAPI 3 document of the mounted routes", nil, map[string]interface{}{}))
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+)
+
+func TestOpenAPIDryRun(t *testing.T) {
+	r := mux.NewRouter()
+	s := r.PathPrefix(apiPrefix).Subrouter()
+
+	noop := func(rw http.ResponseWriter, r *http.Request) {}
+
+	share.Describe(s.HandleFunc("/system/sysctl/add", noop),
+		share.Op("POST", "Add a sysctl", nil, nil).Previewable())
+	share.Describe(s.HandleFunc("/system/group/add", noop),
+		share.Op("POST", "Add a group", nil, nil),
+		share.Op("GET", "List groups", nil, nil))
+
+	doc, err := openAPIDocument(s, &apiMounts[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := doc["paths"].(object)
+
+	tests := []struct {
+		path   string
+		method string
+		dryRun interface{}
+		refuse bool
+	}{
+		{"/api/v1/system/sysctl/add", "post", true, false},
+		{"/api/v1/system/group/add", "post", nil, true},
+		{"/api/v1/system/group/add", "get", nil, false},
+	}
+
+	for _, tt := range tests {
+		op := paths[tt.path].(object)[tt.method].(object)
+
+		if got := op["x-dry-run"]; got != tt.dryRun {
+			t.Errorf("%s %s x-dry-run = %v, want %v", tt.method, tt.path, got, tt.dryRun)
+		}
+
+		_, refuse := op["responses"].(object)["501"]
+		if refuse != tt.refuse {
+			t.Errorf("%s %s documents 501 %v, want %v", tt.method, tt.path, refuse, tt.refuse)
+		}
+	}
+}
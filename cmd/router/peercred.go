@@ -0,0 +1,195 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	authPeerConfPath = "/etc/api-routerd/api-routerd-peers.conf"
+)
+
+type contextKey string
+
+const connContextKey contextKey = "conn"
+
+// connContext stores the accepted connection in the request context
+func connContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey, c)
+}
+
+// peerCredentials reads SO_PEERCRED of a Unix socket connection
+func peerCredentials(r *http.Request) (*unix.Ucred, bool) {
+	c, ok := r.Context().Value(connContextKey).(*net.UnixConn)
+	if !ok {
+		return nil, false
+	}
+
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return nil, false
+	}
+
+	var cred *unix.Ucred
+	var credErr error
+
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	})
+	if err != nil || credErr != nil {
+		log.Errorf("Failed to read peer credentials: %v %v", err, credErr)
+		return nil, false
+	}
+
+	return cred, true
+}
+
+func (db *TokenDB) lookupPeer(r *http.Request) (*AuthUser, bool) {
+	cred, ok := peerCredentials(r)
+	if !ok {
+		return nil, false
+	}
+
+	if u, found := db.peerUIDs[cred.Uid]; found {
+		return u, true
+	}
+
+	gids := []string{strconv.FormatUint(uint64(cred.Gid), 10)}
+
+	// supplementary groups of the peer
+	if u, err := user.LookupId(strconv.FormatUint(uint64(cred.Uid), 10)); err == nil {
+		if g, err := u.GroupIds(); err == nil {
+			gids = append(gids, g...)
+		}
+	}
+
+	for _, g := range gids {
+		gid, err := strconv.ParseUint(g, 10, 32)
+		if err != nil {
+			continue
+		}
+
+		if u, found := db.peerGIDs[uint32(gid)]; found {
+			name := fmt.Sprintf("%s(uid=%d)", u.Name, cred.Uid)
+
+			return &AuthUser{Name: name, Role: u.Role}, true
+		}
+	}
+
+	log.Infof("No role mapped to peer uid=%d gid=%d", cred.Uid, cred.Gid)
+
+	return nil, false
+}
+
+func lookupUID(id string) (uint32, string, error) {
+	u, err := user.Lookup(id)
+	if err != nil {
+		u, err = user.LookupId(id)
+		if err != nil {
+			return 0, "", err
+		}
+	}
+
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return uint32(uid), u.Username, nil
+}
+
+func lookupGID(id string) (uint32, string, error) {
+	g, err := user.LookupGroup(id)
+	if err != nil {
+		g, err = user.LookupGroupId(id)
+		if err != nil {
+			return 0, "", err
+		}
+	}
+
+	gid, err := strconv.ParseUint(g.Gid, 10, 32)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return uint32(gid), g.Name, nil
+}
+
+// initPeerUsers read the Unix socket peer to role mapping
+func (db *TokenDB) initPeerUsers(strict bool) error {
+	if !share.PathExists(authPeerConfPath) {
+		return nil
+	}
+
+	lines, err := share.ReadFullFile(authPeerConfPath)
+	if err != nil {
+		return err
+	}
+
+	// uid|gid <id or name> <role>
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			log.Errorf("Failed to parse peer config line: %s", line)
+			if strict {
+				return fmt.Errorf("Failed to parse peer config line: %s", line)
+			}
+			continue
+		}
+
+		role, err := LookupRole(fields[2])
+		if err != nil {
+			log.Errorf("Failed to assign role to peer %s %s: %s", fields[0], fields[1], err)
+			if strict {
+				return err
+			}
+			continue
+		}
+
+		switch fields[0] {
+		case "uid":
+			uid, name, err := lookupUID(fields[1])
+			if err != nil {
+				log.Errorf("Failed to find user '%s': %s", fields[1], err)
+				if strict {
+					return err
+				}
+				continue
+			}
+
+			db.peerUIDs[uid] = &AuthUser{Name: name, Role: role}
+			break
+		case "gid":
+			gid, name, err := lookupGID(fields[1])
+			if err != nil {
+				log.Errorf("Failed to find group '%s': %s", fields[1], err)
+				if strict {
+					return err
+				}
+				continue
+			}
+
+			db.peerGIDs[gid] = &AuthUser{Name: "@" + name, Role: role}
+			break
+		default:
+			log.Errorf("Failed to parse peer config line: %s", line)
+			if strict {
+				return fmt.Errorf("Failed to parse peer config line: %s", line)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/conf"
+
+	"github.com/coreos/go-systemd/daemon"
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// editors write a file in several steps. Wait for them to settle
+	reloadDelay = 500 * time.Millisecond
+)
+
+// reloader re-reads the conf and auth files on SIGHUP or when they change on disk
+type reloader struct {
+	db     *TokenDB
+	server *server
+	lock   sync.Mutex
+}
+
+func (rl *reloader) reload() {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	log.Info("Reloading configuration")
+
+	notify(daemon.SdNotifyReloading)
+	defer notify(daemon.SdNotifyReady)
+
+	err := conf.ReloadConf()
+	if err != nil {
+		log.Errorf("Rejected invalid conf file, keeping the last good config: %s", err)
+	} else {
+		c := conf.Current()
+
+		specs, err := listenSpecs(c.IPAddress, c.Port, c.UnixSocket, c.Listeners, len(rl.server.activated), rl.server.tlsConfig)
+		if err == nil {
+			err = rl.server.apply(specs)
+		}
+		if err != nil {
+			log.Errorf("Failed to apply listeners, keeping the current ones: %s", err)
+		}
+	}
+
+	err = rl.db.Reload()
+	if err != nil {
+		log.Errorf("Rejected invalid auth config, keeping the current users: %s", err)
+	}
+}
+
+// watchedFiles the files that trigger a reload when they change
+func watchedFiles() map[string]bool {
+	return map[string]bool{
+		path.Join(conf.ConfPath, conf.ConfFile+".toml"): true,
+		conf.Current().Auth.File:                        true,
+		authCertConfPath:                                true,
+		authPeerConfPath:                                true,
+	}
+}
+
+// watch reloads when one of the watched files changes. The directory is watched
+// so files replaced by a rename are picked up as well.
+func (rl *reloader) watch() error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	files := watchedFiles()
+
+	dirs := make(map[string]bool)
+	for f := range files {
+		dirs[filepath.Dir(f)] = true
+	}
+
+	for d := range dirs {
+		err = w.Add(d)
+		if err != nil {
+			w.Close()
+			return err
+		}
+	}
+
+	go func() {
+		var timer *time.Timer
+
+		for {
+			select {
+			case e, ok := <-w.Events:
+				if !ok {
+					return
+				}
+
+				if !files[filepath.Clean(e.Name)] {
+					continue
+				}
+
+				log.Debugf("Config file changed: %s", e)
+
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDelay, rl.reload)
+
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+
+				log.Errorf("Failed to watch config files: %s", err)
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+)
+
+// Built-in roles
+const (
+	RoleReadOnly     = "readonly"
+	RoleNetworkAdmin = "network-admin"
+	RoleAdmin        = "admin"
+
+	// a line without a role must not grant more than reading
+	defaultRole = RoleReadOnly
+)
+
+const (
+	permissionRead  = "read"
+	permissionWrite = "write"
+	permissionAll   = "*"
+)
+
+// subsystems mounted under /api
+var subsystems = []string{
+	"batch",
+	"config",
+	"container",
+	"ext",
+	"health",
+	"jobs",
+	"metrics",
+	"network",
+	"openapi",
+	"plugins",
+	"proc",
+	"service",
+	"system",
+}
+
+//Role a named set of permissions
+type Role struct {
+	Name        string
+	Permissions *share.Set
+}
+
+var roles = map[string]*Role{}
+
+func newRole(name string, permissions ...string) *Role {
+	role := &Role{
+		Name:        name,
+		Permissions: share.NewSet(),
+	}
+
+	for _, p := range permissions {
+		role.Permissions.Add(p)
+	}
+
+	roles[name] = role
+
+	return role
+}
+
+func init() {
+	var readOnly []string
+
+	for _, s := range subsystems {
+		readOnly = append(readOnly, s+":"+permissionRead)
+	}
+
+	newRole(RoleReadOnly, readOnly...)
+	// the steps of a batch are authorized one by one
+	newRole(RoleNetworkAdmin, append(readOnly, "network:"+permissionWrite, "batch:"+permissionWrite)...)
+	newRole(RoleAdmin, permissionAll)
+}
+
+//LookupRole find a role by name
+func LookupRole(name string) (*Role, error) {
+	role, ok := roles[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown role '%s'", name)
+	}
+
+	return role, nil
+}
+
+//Allowed verifies whether the role grants the permission
+func (role *Role) Allowed(permission string) bool {
+	if role.Permissions.Contains(permissionAll) || role.Permissions.Contains(permission) {
+		return true
+	}
+
+	return false
+}
+
+// requiredPermission maps the subrouter and HTTP method to a permission
+func requiredPermission(r *http.Request) string {
+	p := strings.TrimPrefix(r.URL.Path, "/")
+	p = strings.TrimPrefix(p, "api/")
+
+	// versioned paths share the permissions of the legacy ones
+	parts := strings.SplitN(p, "/", 2)
+	if len(parts) == 2 && share.IsAPIVersion(parts[0]) {
+		p = parts[1]
+	}
+
+	// documents such as openapi.json belong to the subsystem of their base name
+	subsystem := strings.SplitN(p, "/", 2)[0]
+	subsystem = strings.TrimSuffix(subsystem, path.Ext(subsystem))
+
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return subsystem + ":" + permissionRead
+	}
+
+	return subsystem + ":" + permissionWrite
+}
@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RestGW/api-routerd/cmd/conf"
+)
+
+func TestRequiredPermission(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{"GET", "/api/network/link/get", "network:read"},
+		{"POST", "/api/network/link/add", "network:write"},
+		{"DELETE", "/api/v1/network/address/delete", "network:write"},
+		{"PUT", "/api/v2/service/systemd/conf", "service:write"},
+		{"HEAD", "/api/v2/system/hostname/get", "system:read"},
+		{"OPTIONS", "/api/proc/misc", "proc:read"},
+		{"GET", "/api/v1/openapi.json", "openapi:read"},
+		{"POST", "/api/batch", "batch:write"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+
+		got := requiredPermission(r)
+		if got != tt.want {
+			t.Errorf("requiredPermission(%s %s) = %s, want %s", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRoles(t *testing.T) {
+	tests := []struct {
+		role       string
+		permission string
+		want       bool
+	}{
+		{RoleReadOnly, "network:read", true},
+		{RoleReadOnly, "network:write", false},
+		{RoleNetworkAdmin, "network:write", true},
+		{RoleNetworkAdmin, "system:write", false},
+		{RoleNetworkAdmin, "batch:write", true},
+		{RoleAdmin, "system:write", true},
+	}
+
+	for _, tt := range tests {
+		role, err := LookupRole(tt.role)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := role.Allowed(tt.permission); got != tt.want {
+			t.Errorf("%s allowed %s = %v, want %v", tt.role, tt.permission, got, tt.want)
+		}
+	}
+
+	_, err := LookupRole("root")
+	if err == nil {
+		t.Error("LookupRole accepted an unknown role")
+	}
+}
+
+func TestDefaultRoleIsReadOnly(t *testing.T) {
+	user, err := parseUserRole([]string{"joy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if user.Role.Name != RoleReadOnly {
+		t.Errorf("user without role got '%s', want '%s'", user.Role.Name, RoleReadOnly)
+	}
+}
+
+func newTestTokenDB(t *testing.T, lines ...string) *TokenDB {
+	file := filepath.Join(t.TempDir(), "auth.conf")
+
+	err := ioutil.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db := newTokenDB(nil)
+	db.file = file
+	db.mode = conf.AuthModeToken
+
+	err = db.initUsers(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestAuthMiddlewareForbidden(t *testing.T) {
+	db := newTestTokenDB(t, "max netsecret network-admin", "joy joysecret")
+
+	h := db.AuthMiddleware(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		token  string
+		method string
+		path   string
+		status int
+		reason string
+	}{
+		{"netsecret", "POST", "/api/network/link/add", http.StatusOK, ""},
+		{"netsecret", "POST", "/api/system/hostname/set", http.StatusForbidden, "system:write"},
+		{"joysecret", "GET", "/api/system/hostname/get", http.StatusOK, ""},
+		{"joysecret", "POST", "/api/network/link/add", http.StatusForbidden, "network:write"},
+		{"wrong", "GET", "/api/network/link/get", http.StatusForbidden, "Forbidden"},
+		{"", "GET", "/api/network/link/get", http.StatusForbidden, "Forbidden"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		r.Header.Set("X-Session-Token", tt.token)
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tt.status {
+			t.Errorf("%s %s with '%s' = %d, want %d", tt.method, tt.path, tt.token, w.Code, tt.status)
+		}
+
+		if !strings.Contains(w.Body.String(), tt.reason) {
+			t.Errorf("%s %s with '%s' replied '%s', want '%s'", tt.method, tt.path, tt.token, w.Body.String(), tt.reason)
+		}
+	}
+}
@@ -6,101 +6,231 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/batch"
+	"github.com/RestGW/api-routerd/cmd/conf"
 	"github.com/RestGW/api-routerd/cmd/container"
+	"github.com/RestGW/api-routerd/cmd/ext"
+	"github.com/RestGW/api-routerd/cmd/health"
+	"github.com/RestGW/api-routerd/cmd/history"
+	"github.com/RestGW/api-routerd/cmd/jobs"
+	"github.com/RestGW/api-routerd/cmd/metrics"
 	"github.com/RestGW/api-routerd/cmd/network"
+	"github.com/RestGW/api-routerd/cmd/plugins"
 	"github.com/RestGW/api-routerd/cmd/proc"
 	"github.com/RestGW/api-routerd/cmd/share"
 	"github.com/RestGW/api-routerd/cmd/system"
 	"github.com/RestGW/api-routerd/cmd/systemd"
-	"net/http"
-	"os"
-	"os/signal"
-	"syscall"
 
-	"github.com/coreos/go-systemd/activation"
+	"github.com/coreos/go-systemd/daemon"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 )
 
-//StartRouter Init and start Gorilla mux router
-func StartRouter(ip string, port string, tlsCertPath string, tlsKeyPath string) error {
-	var srv http.Server
+const (
+	shutdownTimeout = 30 * time.Second
+)
+
+// registerModules mounts the enabled modules
+func registerModules(s *mux.Router) {
+	if conf.ModuleEnabled("container") {
+		container.RegisterRouterContainer(s)
+	}
+
+	if conf.ModuleEnabled("network") {
+		network.RegisterRouterNetwork(s)
+	}
+
+	if conf.ModuleEnabled("proc") {
+		proc.RegisterRouterProc(s)
+	}
+
+	if conf.ModuleEnabled("systemd") {
+		systemd.RegisterRouterSystemd(s)
+	}
 
+	if conf.ModuleEnabled("system") {
+		system.RegisterRouterSystem(s)
+	}
+
+	if conf.ModuleEnabled("plugins") {
+		plugins.RegisterRouterPlugins(s)
+	}
+
+	if conf.ModuleEnabled("ext") {
+		ext.RegisterRouterExt(s)
+	}
+}
+
+//StartRouter Init and start Gorilla mux router
+func StartRouter(ip string, port string, unixSocket string, listen []conf.Listen, tlsConf *conf.TLSConf) error {
 	r := mux.NewRouter()
-	s := r.PathPrefix("/api").Subrouter()
+	r.NotFoundHandler = http.HandlerFunc(share.RouteNotFound)
+	r.MethodNotAllowedHandler = http.HandlerFunc(share.MethodNotAllowed)
 
-	// Register services
-	container.RegisterRouterContainer(s)
-	network.RegisterRouterNetwork(s)
-	proc.RegisterRouterProc(s)
-	systemd.RegisterRouterSystemd(s)
-	system.RegisterRouterSystem(s)
+	// API tokens
+	store, err := NewTokenStore()
+	if err != nil {
+		log.Fatalf("Failed to load token store: %s", err)
+		return fmt.Errorf("Failed to load token store: %s", err)
+	}
 
 	// Authenticate users
-	amw, err := InitAuthMiddleware()
+	amw, err := InitAuthMiddleware(store)
 	if err != nil {
 		log.Fatalf("Faild to init auth DB existing: %s", err)
 		return fmt.Errorf("Failed to init Auth DB: %s", err)
 	}
 
+	// Audit mutating calls
+	auditConf := conf.Current().Audit
+	audit, err := NewAuditLog(auditConf.File, auditConf.Journal)
+	if err != nil {
+		log.Fatalf("Failed to open audit log %s: %s", auditConf.File, err)
+		return fmt.Errorf("Failed to open audit log: %s", err)
+	}
+	amw.OnDenied(audit.Denied)
+
+	// Go plugins
+	if conf.ModuleEnabled("plugins") {
+		dir := path.Join(conf.ConfPath, conf.PluginsDir)
+
+		err = plugins.Load(dir)
+		if err != nil && !os.IsNotExist(err) {
+			log.Errorf("Failed to load plugins from %s: %s", dir, err)
+		}
+	}
+
+	// executable plugins
+	if conf.ModuleEnabled("ext") {
+		dir := path.Join(conf.ConfPath, conf.ExtDir)
+
+		err = ext.Load(dir)
+		if err != nil && !os.IsNotExist(err) {
+			log.Errorf("Failed to load ext plugins from %s: %s", dir, err)
+		}
+	}
+
+	// Mount every module once, below all API versions
+	s := r.PathPrefix(apiPrefix).Subrouter()
+	s.Use(versionMiddleware)
+
+	registerModules(s)
+	registerRouterAuth(s, store)
+	registerRouterAudit(s, audit)
+	jobs.RegisterRouterJobs(s)
+	history.RegisterRouterHistory(s)
+	batch.RegisterRouterBatch(s, r)
+	health.RegisterRouterHealthReport(s)
+	registerRouterOpenAPI(s)
+
+	// probes of load balancers
+	health.RegisterRouterHealth(r)
+
+	// Prometheus scrapes /metrics outside of /api
+	if conf.ModuleEnabled("metrics") {
+		metrics.RegisterRouterMetrics(r)
+	}
+
+	r.Use(metricsMiddleware)
 	r.Use(amw.AuthMiddleware)
+	r.Use(audit.AuditMiddleware)
+	r.Use(dryRunMiddleware)
+
+	var tlsConfig *tls.Config
+	if share.PathExists(tlsConf.Cert) && share.PathExists(tlsConf.Key) {
+		tlsConfig, err = newTLSConfig(tlsConf)
+		if err != nil {
+			log.Fatalf("Failed to init TLS: %s", err)
+			return err
+		}
+	}
+
+	activated, err := activatedListeners(listen, tlsConfig)
+	if err != nil {
+		log.Fatalf("Failed to init listeners: %s", err)
+		return err
+	}
+
+	specs, err := listenSpecs(ip, port, unixSocket, listen, len(activated), tlsConfig)
+	if err != nil {
+		log.Fatalf("Failed to init listeners: %s", err)
+		return err
+	}
+
+	srv := &http.Server{
+		Handler:      r,
+		TLSNextProto: make(map[string]func(*http.Server, *tls.Conn, http.Handler), 0),
+		ConnContext:  connContext,
+	}
+
+	// event streams never go idle, end them so Shutdown can drain
+	srv.RegisterOnShutdown(share.StopStreams)
+
+	server := newServer(srv, tlsConfig, activated)
+	rl := &reloader{db: amw, server: server}
 
-	var gracefulStop = make(chan os.Signal)
+	err = rl.watch()
+	if err != nil {
+		log.Errorf("Failed to watch config files, reload with SIGHUP only: %s", err)
+	}
+
+	stopped := make(chan struct{})
+
+	var gracefulStop = make(chan os.Signal, 1)
 	signal.Notify(gracefulStop, syscall.SIGTERM)
 	signal.Notify(gracefulStop, syscall.SIGINT)
+	signal.Notify(gracefulStop, syscall.SIGHUP)
 	go func() {
-		sig := <-gracefulStop
+		for sig := range gracefulStop {
+			log.Printf("Received signal: %+v", sig)
+
+			if sig == syscall.SIGHUP {
+				rl.reload()
+				continue
+			}
+
+			break
+		}
 
-		log.Printf("Received signal: %+v", sig)
 		log.Println("Shutting down api-routerd ...")
+		notify(daemon.SdNotifyStopping)
 
-		err := srv.Shutdown(context.Background())
+		// stop accepting on every listener and drain in-flight requests
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		err := srv.Shutdown(ctx)
 		if err != nil {
 			log.Errorf("Failed to shutdown server gracefuly: %s", err)
 		}
 
-		os.Exit(0)
+		ext.Stop()
+
+		close(stopped)
 	}()
 
-	// socket activation
-	listeners, err := activation.Listeners()
+	err = server.start(specs)
 	if err != nil {
-		log.Infof("Failed to retrieve listeners: %s", err)
+		log.Fatalf("Failed to init listeners: %s", err)
+		return err
 	}
 
-	if share.PathExists(tlsCertPath) && share.PathExists(tlsKeyPath) {
-		cfg := &tls.Config{
-			MinVersion:               tls.VersionTLS12,
-			CurvePreferences:         []tls.CurveID{tls.CurveP521, tls.CurveP384, tls.CurveP256},
-			PreferServerCipherSuites: false,
-		}
-		srv = http.Server{
-			Addr:         ip + ":" + port,
-			Handler:      r,
-			TLSConfig:    cfg,
-			TLSNextProto: make(map[string]func(*http.Server, *tls.Conn, http.Handler), 0),
-		}
-
-		log.Info("Starting api-routerd in TLS mode")
+	notify(daemon.SdNotifyReady + "\n" + server.status())
+	go server.supervise(stopped)
 
-		if len(listeners) <= 0 {
-			log.Fatal(srv.ListenAndServeTLS(tlsCertPath, tlsKeyPath))
-		} else {
-			log.Fatal(srv.ServeTLS(listeners[0], tlsCertPath, tlsKeyPath))
-		}
-	} else {
-		srv = http.Server{
-			Addr:    ip + ":" + port,
-			Handler: r,
-		}
-		log.Info("Starting api-routerd in plain text mode")
-
-		if len(listeners) <= 0 {
-			log.Fatal(srv.ListenAndServe())
-		} else {
-			log.Fatal(srv.Serve(listeners[0]))
-		}
+	err = server.wait()
+	if err != nil {
+		log.Fatal(err)
 	}
 
+	<-stopped
+
 	return nil
 }
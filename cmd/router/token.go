@@ -0,0 +1,295 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/conf"
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	tokenStoreFile = "api-routerd-tokens.json"
+	tokenBytes     = 32
+)
+
+//Token API token managed via /api/auth/tokens
+type Token struct {
+	ID      string     `json:"id"`
+	Name    string     `json:"name"`
+	Role    string     `json:"role"`
+	Sources []string   `json:"sources,omitempty"`
+	Created time.Time  `json:"created"`
+	Expires *time.Time `json:"expires,omitempty"`
+	Hash    string     `json:"hash,omitempty"`
+
+	// Only set once in the reply to create
+	Secret string `json:"token,omitempty"`
+}
+
+//TokenRequest JSON request to create a token
+type TokenRequest struct {
+	Name      string   `json:"name"`
+	Role      string   `json:"role"`
+	Sources   []string `json:"sources"`
+	ExpiresIn string   `json:"expires_in"`
+}
+
+//TokenStore tokens persisted under conf.ConfPath
+type TokenStore struct {
+	path   string
+	tokens map[string]*Token
+	lock   sync.RWMutex
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(h[:])
+}
+
+//NewTokenStore loads the token store
+func NewTokenStore() (*TokenStore, error) {
+	s := &TokenStore{
+		path:   path.Join(conf.ConfPath, tokenStoreFile),
+		tokens: make(map[string]*Token),
+	}
+
+	if !share.PathExists(s.path) {
+		return s, nil
+	}
+
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []*Token
+	err = json.Unmarshal(b, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse token store %s: %s", s.path, err)
+	}
+
+	for _, t := range tokens {
+		_, err = LookupRole(t.Role)
+		if err != nil {
+			log.Errorf("Ignoring token %s (%s): %s", t.ID, t.Name, err)
+			continue
+		}
+
+		s.tokens[t.ID] = t
+	}
+
+	return s, nil
+}
+
+// save write the store. Caller must hold the lock
+func (s *TokenStore) save() error {
+	tokens := make([]*Token, 0, len(s.tokens))
+	for _, t := range s.tokens {
+		tokens = append(tokens, t)
+	}
+
+	b, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return share.WriteFileAtomic(s.path, b, 0600)
+}
+
+//Create issue a new token
+func (s *TokenStore) Create(req *TokenRequest) (*Token, error) {
+	if req.Name == "" {
+		return nil, share.BadRequest(fmt.Errorf("Token name is required"))
+	}
+
+	if req.Role == "" {
+		req.Role = RoleReadOnly
+	}
+
+	_, err := LookupRole(req.Role)
+	if err != nil {
+		return nil, share.BadRequest(err)
+	}
+
+	for _, src := range req.Sources {
+		_, _, err = net.ParseCIDR(src)
+		if err != nil {
+			if net.ParseIP(src) == nil {
+				return nil, share.BadRequest(fmt.Errorf("Failed to parse source '%s'", src))
+			}
+		}
+	}
+
+	t := &Token{
+		Name:    req.Name,
+		Role:    req.Role,
+		Sources: req.Sources,
+		Created: time.Now().UTC(),
+	}
+
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 {
+			return nil, share.BadRequest(fmt.Errorf("Failed to parse expires_in '%s'", req.ExpiresIn))
+		}
+
+		expires := t.Created.Add(d)
+		t.Expires = &expires
+	}
+
+	t.ID, err = randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := randomHex(tokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	t.Hash = hashToken(secret)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.tokens[t.ID] = t
+	err = s.save()
+	if err != nil {
+		delete(s.tokens, t.ID)
+		return nil, fmt.Errorf("Failed to save token store: %s", err)
+	}
+
+	reply := *t
+	reply.Hash = ""
+	reply.Secret = secret
+
+	return &reply, nil
+}
+
+//List all tokens without their hashes
+func (s *TokenStore) List() []Token {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	tokens := make([]Token, 0, len(s.tokens))
+	for _, t := range s.tokens {
+		c := *t
+		c.Hash = ""
+		tokens = append(tokens, c)
+	}
+
+	return tokens
+}
+
+//Revoke remove a token
+func (s *TokenStore) Revoke(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	t, ok := s.tokens[id]
+	if !ok {
+		return share.NotFound(fmt.Errorf("Token '%s' not found", id))
+	}
+
+	delete(s.tokens, id)
+	err := s.save()
+	if err != nil {
+		s.tokens[id] = t
+		return fmt.Errorf("Failed to save token store: %s", err)
+	}
+
+	return nil
+}
+
+func sourceAllowed(sources []string, remoteAddr string) bool {
+	if len(sources) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	for _, src := range sources {
+		_, n, err := net.ParseCIDR(src)
+		if err == nil {
+			if n.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		if net.ParseIP(src).Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Lookup find a valid token for the secret and source address
+func (s *TokenStore) Lookup(secret string, remoteAddr string) (*AuthUser, bool) {
+	if secret == "" {
+		return nil, false
+	}
+
+	h := hashToken(secret)
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for _, t := range s.tokens {
+		if t.Hash != h {
+			continue
+		}
+
+		if t.Expires != nil && time.Now().After(*t.Expires) {
+			log.Infof("Token %s (%s) expired", t.ID, t.Name)
+			return nil, false
+		}
+
+		if !sourceAllowed(t.Sources, remoteAddr) {
+			log.Infof("Token %s (%s) used from disallowed source %s", t.ID, t.Name, remoteAddr)
+			return nil, false
+		}
+
+		role, err := LookupRole(t.Role)
+		if err != nil {
+			return nil, false
+		}
+
+		return &AuthUser{Name: t.Name, Role: role}, true
+	}
+
+	return nil, false
+}
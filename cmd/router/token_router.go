@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+)
+
+func (s *TokenStore) routerTokens(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		err := share.JSONResponse(s.List(), rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+		break
+
+	case "POST":
+		req := new(TokenRequest)
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			share.HTTPError(rw, share.BadRequest(err))
+			return
+		}
+
+		t, err := s.Create(req)
+		if err != nil {
+			share.HTTPError(rw, err)
+			return
+		}
+
+		share.JSONResponse(t, rw)
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+func (s *TokenStore) routerRevokeToken(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	switch r.Method {
+	case "DELETE":
+		err := s.Revoke(id)
+		if err != nil {
+			share.HTTPError(rw, err)
+			return
+		}
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+// registerRouterAuth register the token lifecycle endpoints with mux
+func registerRouterAuth(router *mux.Router, s *TokenStore) {
+	n := router.PathPrefix("/auth").Subrouter().StrictSlash(false)
+
+	share.Describe(n.HandleFunc("/tokens", s.routerTokens),
+		share.Op("GET", "List API tokens", nil, []Token{}),
+		share.Op("POST", "Create an API token", TokenRequest{}, Token{}))
+	share.Describe(n.HandleFunc("/tokens/{id}", s.routerRevokeToken),
+		share.Op("DELETE", "Revoke an API token", nil, nil))
+}
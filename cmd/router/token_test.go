@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func newTestTokenStore(t *testing.T) *TokenStore {
+	return &TokenStore{
+		path:   filepath.Join(t.TempDir(), tokenStoreFile),
+		tokens: make(map[string]*Token),
+	}
+}
+
+func revoke(s *TokenStore, id string) *httptest.ResponseRecorder {
+	router := mux.NewRouter()
+	registerRouterAuth(router, s)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/auth/tokens/"+id, nil))
+
+	return w
+}
+
+func TestRevokeToken(t *testing.T) {
+	s := newTestTokenStore(t)
+
+	tok, err := s.Create(&TokenRequest{Name: "noc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w := revoke(s, "unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("revoking an unknown token replied %d, want 404", w.Code)
+	}
+
+	if w := revoke(s, tok.ID); w.Code != http.StatusOK {
+		t.Errorf("revoking a token replied %d: %s", w.Code, w.Body.String())
+	}
+
+	if _, found := s.Lookup(tok.Secret, "127.0.0.1:1234"); found {
+		t.Error("revoked token still valid")
+	}
+}
+
+func TestRevokeTokenSaveFails(t *testing.T) {
+	s := newTestTokenStore(t)
+
+	tok, err := s.Create(&TokenRequest{Name: "noc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the store can not be written any more
+	s.path = filepath.Join(t.TempDir(), "missing", tokenStoreFile)
+
+	if w := revoke(s, tok.ID); w.Code != http.StatusInternalServerError {
+		t.Errorf("failed save replied %d, want 500", w.Code)
+	}
+
+	if _, found := s.Lookup(tok.Secret, "127.0.0.1:1234"); !found {
+		t.Error("token not kept after a failed save")
+	}
+}
+
+func TestCreateTokenInvalid(t *testing.T) {
+	s := newTestTokenStore(t)
+
+	for _, req := range []TokenRequest{
+		{},
+		{Name: "noc", Role: "root"},
+		{Name: "noc", Sources: []string{"nowhere"}},
+		{Name: "noc", ExpiresIn: "-1h"},
+	} {
+		req := req
+
+		_, err := s.Create(&req)
+		if err == nil {
+			t.Errorf("token request %+v accepted", req)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/conf"
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	legacyPrefix = "/api"
+
+	// apiPrefix matches every mount, the modules are registered once below it
+	apiPrefix = legacyPrefix + "{apiVersion:(?:/" + share.APIv1 + "|/" + share.APIv2 + ")?}"
+)
+
+// apiMount a path prefix the modules are mounted under and the API version it serves
+type apiMount struct {
+	prefix     string
+	version    string
+	deprecated bool
+}
+
+// apiMounts versioned prefixes first so the legacy /api prefix does not shadow them
+var apiMounts = []apiMount{
+	{prefix: "/api/" + share.APIv1, version: share.APIv1},
+	{prefix: "/api/" + share.APIv2, version: share.APIv2},
+	// unversioned paths keep the v1 shapes until the sunset date
+	{prefix: legacyPrefix, version: share.APIv1, deprecated: true},
+}
+
+// requestMount the mount a request was routed through, the legacy one
+// outside of the API prefix
+func requestMount(r *http.Request) *apiMount {
+	prefix := legacyPrefix + mux.Vars(r)["apiVersion"]
+
+	for i := range apiMounts {
+		if apiMounts[i].prefix == prefix {
+			return &apiMounts[i]
+		}
+	}
+
+	return &apiMounts[len(apiMounts)-1]
+}
+
+// mountTemplate the path template of route below the prefix of m
+func mountTemplate(route *mux.Route, m *apiMount) (string, error) {
+	t, err := route.GetPathTemplate()
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(t, apiPrefix) {
+		t = m.prefix + strings.TrimPrefix(t, apiPrefix)
+	}
+
+	return t, nil
+}
+
+// routeTemplate the path template a request was routed to, with the prefix
+// it was sent to
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+
+	t, _ := mountTemplate(route, requestMount(r))
+
+	return t
+}
+
+// sunset the HTTP date of the configured [API] Sunset
+func sunset() string {
+	t, err := time.Parse("2006-01-02", conf.Current().API.Sunset)
+	if err != nil {
+		t, _ = time.Parse("2006-01-02", conf.LegacySunset)
+	}
+
+	return t.UTC().Format(http.TimeFormat)
+}
+
+// versionMiddleware tags requests with the API version of their mount and
+// announces the deprecation of the legacy paths
+func versionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		m := requestMount(r)
+
+		if m.deprecated {
+			successor := "/api/" + m.version + strings.TrimPrefix(r.URL.Path, legacyPrefix)
+
+			rw.Header().Set("Deprecation", "true")
+			rw.Header().Set("Sunset", sunset())
+			rw.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+		}
+
+		next.ServeHTTP(rw, share.WithAPIVersion(r, m.version))
+	})
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	"github.com/gorilla/mux"
+)
+
+func TestAPIMounts(t *testing.T) {
+	r := mux.NewRouter()
+
+	s := r.PathPrefix(apiPrefix).Subrouter()
+	s.Use(versionMiddleware)
+
+	s.HandleFunc("/network/link/{link}", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("X-Version", share.APIVersion(r))
+		rw.Header().Set("X-Route", routeTemplate(r))
+		rw.Header().Set("X-Link", mux.Vars(r)["link"])
+	})
+
+	registerRouterOpenAPI(s)
+
+	tests := []struct {
+		path       string
+		version    string
+		route      string
+		deprecated bool
+	}{
+		{"/api/v1/network/link/eth0", share.APIv1, "/api/v1/network/link/{link}", false},
+		{"/api/v2/network/link/eth0", share.APIv2, "/api/v2/network/link/{link}", false},
+		{"/api/network/link/eth0", share.APIv1, "/api/network/link/{link}", true},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d", tt.path, w.Code)
+		}
+
+		h := w.Header()
+		if h.Get("X-Version") != tt.version || h.Get("X-Route") != tt.route || h.Get("X-Link") != "eth0" {
+			t.Errorf("GET %s routed to version '%s' route '%s' link '%s'", tt.path, h.Get("X-Version"), h.Get("X-Route"), h.Get("X-Link"))
+		}
+
+		if (h.Get("Deprecation") != "") != tt.deprecated {
+			t.Errorf("GET %s Deprecation '%s', deprecated %v", tt.path, h.Get("Deprecation"), tt.deprecated)
+		}
+	}
+
+	for i := range apiMounts {
+		m := &apiMounts[i]
+
+		doc, err := openAPIDocument(s, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		paths := doc["paths"].(object)
+		if _, ok := paths[m.prefix+"/openapi.json"]; !ok {
+			t.Errorf("OpenAPI document of %s lacks %s/openapi.json: %v", m.prefix, m.prefix, paths)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"bytes"
+	"net/http"
+)
+
+//ResponseBuffer holds back the reply of a handler so a middleware can
+//replace it or pass it on
+type ResponseBuffer struct {
+	header http.Header
+	Status int
+	Body   bytes.Buffer
+}
+
+//NewResponseBuffer an empty reply
+func NewResponseBuffer() *ResponseBuffer {
+	return &ResponseBuffer{header: make(http.Header)}
+}
+
+//Header the headers set by the handler
+func (w *ResponseBuffer) Header() http.Header {
+	return w.header
+}
+
+//WriteHeader remembers the first status code
+func (w *ResponseBuffer) WriteHeader(code int) {
+	if w.Status == 0 {
+		w.Status = code
+	}
+}
+
+func (w *ResponseBuffer) Write(b []byte) (int, error) {
+	if w.Status == 0 {
+		w.Status = http.StatusOK
+	}
+
+	return w.Body.Write(b)
+}
+
+//Failed whether the handler replied an error
+func (w *ResponseBuffer) Failed() bool {
+	return w.Status >= http.StatusBadRequest
+}
+
+//Replay sends the held back reply
+func (w *ResponseBuffer) Replay(rw http.ResponseWriter) {
+	for k, v := range w.header {
+		rw.Header()[k] = v
+	}
+
+	if w.Status != 0 {
+		rw.WriteHeader(w.Status)
+	}
+
+	rw.Write(w.Body.Bytes())
+}
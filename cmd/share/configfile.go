@@ -0,0 +1,349 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	defaultBackupDir  = "/var/lib/api-routerd/backup"
+	defaultBackupKeep = 5
+
+	// sorts in time order
+	backupTimeFormat = "20060102T150405.000000000"
+
+	selinuxXattr = "security.selinux"
+)
+
+//BackupConfig the [Backup] section of the conf file. The last Keep versions
+//of every configuration file are kept below Dir, 0 disables backups
+type BackupConfig struct {
+	Dir  string
+	Keep int
+}
+
+var backup = struct {
+	sync.RWMutex
+	conf BackupConfig
+}{conf: DefaultBackupConfig()}
+
+// serializes writers of the same file so backups and renames do not interleave
+var configFileLocks = struct {
+	sync.Mutex
+	m map[string]*sync.Mutex
+}{m: make(map[string]*sync.Mutex)}
+
+//DefaultBackupConfig keep 5 versions below /var/lib/api-routerd/backup
+func DefaultBackupConfig() BackupConfig {
+	return BackupConfig{
+		Dir:  defaultBackupDir,
+		Keep: defaultBackupKeep,
+	}
+}
+
+//SetBackupConfig sets where and how many backups WriteConfigFile keeps
+func SetBackupConfig(c BackupConfig) {
+	backup.Lock()
+	defer backup.Unlock()
+
+	backup.conf = c
+}
+
+func backupConfig() BackupConfig {
+	backup.RLock()
+	defer backup.RUnlock()
+
+	return backup.conf
+}
+
+func configFileLock(path string) *sync.Mutex {
+	configFileLocks.Lock()
+	defer configFileLocks.Unlock()
+
+	l, ok := configFileLocks.m[path]
+	if !ok {
+		l = new(sync.Mutex)
+		configFileLocks.m[path] = l
+	}
+
+	return l
+}
+
+//BackupFiles the backups of path, oldest first
+func BackupFiles(path string) ([]string, error) {
+	name := filepath.Join(backupConfig().Dir, path)
+
+	files, err := ioutil.ReadDir(filepath.Dir(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	prefix := filepath.Base(name) + "."
+
+	var backups []string
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name(), prefix) {
+			continue
+		}
+
+		_, err := time.Parse(backupTimeFormat, strings.TrimPrefix(f.Name(), prefix))
+		if err == nil {
+			backups = append(backups, filepath.Join(filepath.Dir(name), f.Name()))
+		}
+	}
+	sort.Strings(backups)
+
+	return backups, nil
+}
+
+// backupFile copies the current content of path below the backup dir and
+// drops the oldest backups beyond Keep
+func backupFile(path string, content []byte) error {
+	c := backupConfig()
+	if c.Keep <= 0 {
+		return nil
+	}
+
+	name := filepath.Join(c.Dir, path) + "." + time.Now().UTC().Format(backupTimeFormat)
+
+	err := os.MkdirAll(filepath.Dir(name), 0700)
+	if err != nil {
+		return err
+	}
+
+	err = WriteFileAtomic(name, content, 0600)
+	if err != nil {
+		return err
+	}
+
+	backups, err := BackupFiles(path)
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > c.Keep {
+		os.Remove(backups[0])
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// selinuxLabel the SELinux context of path, nil without SELinux
+func selinuxLabel(path string) []byte {
+	n, err := unix.Lgetxattr(path, selinuxXattr, nil)
+	if err != nil || n <= 0 {
+		return nil
+	}
+
+	buf := make([]byte, n)
+	n, err = unix.Lgetxattr(path, selinuxXattr, buf)
+	if err != nil {
+		return nil
+	}
+
+	return buf[:n]
+}
+
+// syncDir makes a rename in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+//WriteFileAtomic replaces path with content so readers see either the old or
+//the new file, never a partial one. A new file gets perm, an existing file
+//keeps its owner, mode and SELinux label. Symlinks are followed
+func WriteFileAtomic(path string, content []byte, perm os.FileMode) error {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+
+		target = path
+	}
+
+	mode := perm
+	uid, gid := -1, -1
+	var label []byte
+
+	st, err := os.Stat(target)
+	if err == nil {
+		mode = st.Mode().Perm()
+
+		if s, ok := st.Sys().(*syscall.Stat_t); ok {
+			uid, gid = int(s.Uid), int(s.Gid)
+		}
+
+		label = selinuxLabel(target)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	dir, base := filepath.Split(target)
+	if dir == "" {
+		dir = "."
+	}
+
+	f, err := ioutil.TempFile(dir, "."+base+".")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	err = writeTemp(f, content, mode, uid, gid, label)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to write '%s': %s", target, err)
+	}
+
+	err = os.Rename(tmp, target)
+	if err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+func writeTemp(f *os.File, content []byte, mode os.FileMode, uid int, gid int, label []byte) error {
+	_, err := f.Write(content)
+	if err != nil {
+		return err
+	}
+
+	err = f.Chmod(mode)
+	if err != nil {
+		return err
+	}
+
+	if uid >= 0 {
+		err = f.Chown(uid, gid)
+		if err != nil {
+			return err
+		}
+	}
+
+	if label != nil {
+		err = unix.Fsetxattr(int(f.Fd()), selinuxXattr, label, 0)
+		if err != nil && err != unix.ENOTSUP {
+			return err
+		}
+	}
+
+	return f.Sync()
+}
+
+// writeConfigFile backs up the current version of path, replaces it and
+// records the new content in its history. It returns the content it
+// replaced, nil for a new file, and the revision it recorded
+func writeConfigFile(path string, content []byte, perm os.FileMode, author string, operation string) ([]byte, *Revision, error) {
+	l := configFileLock(path)
+	l.Lock()
+	defer l.Unlock()
+
+	old, err := ioutil.ReadFile(path)
+	if err == nil {
+		err = backupFile(path, old)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to back up '%s': %s", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+
+	err = WriteFileAtomic(path, content, perm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the file is written, a lost revision must not fail the request
+	rev, err := recordRevision(path, old, content, false, author, operation)
+	if err != nil {
+		log.Errorf("Failed to record revision of '%s': %s", path, err)
+	}
+
+	return old, rev, nil
+}
+
+// removeConfigFile backs up path, removes it and records the deletion in
+// its history. It returns the content it removed, nil when path did not
+// exist, and the revision it recorded
+func removeConfigFile(path string, author string, operation string) ([]byte, *Revision, error) {
+	l := configFileLock(path)
+	l.Lock()
+	defer l.Unlock()
+
+	old, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+
+		return nil, nil, err
+	}
+
+	err = backupFile(path, old)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to back up '%s': %s", path, err)
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the file is gone, a lost revision must not fail the request
+	rev, err := recordRevision(path, old, nil, true, author, operation)
+	if err != nil {
+		log.Errorf("Failed to record deletion of '%s': %s", path, err)
+	}
+
+	return old, rev, nil
+}
+
+//WriteConfigFile writes content to a configuration file atomically after
+//backing up the current version. A dry run request records the change
+//instead of touching disk, a transaction how to undo it
+func WriteConfigFile(r *http.Request, path string, content string, perm os.FileMode) error {
+	if p := DryRun(r); p != nil {
+		return p.Add(path, content)
+	}
+
+	operation := r.Method + " " + r.URL.Path
+
+	old, _, err := writeConfigFile(path, []byte(content), perm, requestAuthor(r), operation)
+	if err != nil {
+		return err
+	}
+
+	undoConfigFile(r, path, old, perm, operation)
+
+	return nil
+}
@@ -0,0 +1,253 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// testBackups keeps backups and history below a temp dir for the test
+func testBackups(t *testing.T, keep int) string {
+	dir := t.TempDir()
+
+	SetBackupConfig(BackupConfig{Dir: filepath.Join(dir, "backup"), Keep: keep})
+	SetHistoryConfig(HistoryConfig{Dir: filepath.Join(dir, "history"), Keep: 0})
+
+	t.Cleanup(func() {
+		SetBackupConfig(DefaultBackupConfig())
+		SetHistoryConfig(DefaultHistoryConfig())
+	})
+
+	return dir
+}
+
+func readFile(t *testing.T, path string) string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+// noTempFiles fails when a write left its temp file behind in dir
+func noTempFiles(t *testing.T, dir string, want int) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != want {
+		t.Errorf("%d files in %s, want %d", len(files), dir, want)
+	}
+}
+
+func TestWriteFileAtomicNewFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "10-eth0.network")
+
+	err := WriteFileAtomic(path, []byte("[Match]\nName=eth0\n"), 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, path); got != "[Match]\nName=eth0\n" {
+		t.Errorf("content '%s'", got)
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if st.Mode().Perm() != 0640 {
+		t.Errorf("new file mode %o, want 640", st.Mode().Perm())
+	}
+
+	noTempFiles(t, dir, 1)
+}
+
+func TestWriteFileAtomicKeepsMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "resolved.conf")
+
+	err := ioutil.WriteFile(path, []byte("old\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chmod(path, 0604)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = WriteFileAtomic(path, []byte("new\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if st.Mode().Perm() != 0604 {
+		t.Errorf("mode %o, want the existing 604", st.Mode().Perm())
+	}
+
+	if got := readFile(t, path); got != "new\n" {
+		t.Errorf("content '%s'", got)
+	}
+
+	noTempFiles(t, dir, 1)
+}
+
+func TestWriteFileAtomicKeepsOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner needs root")
+	}
+
+	path := filepath.Join(t.TempDir(), "timesyncd.conf")
+
+	err := ioutil.WriteFile(path, []byte("old\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Chown(path, 1234, 5678)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = WriteFileAtomic(path, []byte("new\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := st.Sys().(*syscall.Stat_t)
+	if s.Uid != 1234 || s.Gid != 5678 {
+		t.Errorf("owner %d:%d, want 1234:5678", s.Uid, s.Gid)
+	}
+}
+
+func TestWriteFileAtomicKeepsLabel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journald.conf")
+
+	err := ioutil.WriteFile(path, []byte("old\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	label := []byte("system_u:object_r:etc_t:s0\x00")
+
+	err = unix.Lsetxattr(path, selinuxXattr, label, 0)
+	if err != nil {
+		t.Skipf("Setting %s not supported here: %s", selinuxXattr, err)
+	}
+
+	err = WriteFileAtomic(path, []byte("new\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := selinuxLabel(path); !bytes.Equal(got, label) {
+		t.Errorf("label '%s', want '%s'", got, label)
+	}
+}
+
+func TestWriteFileAtomicSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "resolv.conf.real")
+	link := filepath.Join(dir, "resolv.conf")
+
+	err := ioutil.WriteFile(target, []byte("nameserver 10.0.0.1\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Symlink(filepath.Base(target), link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = WriteFileAtomic(link, []byte("nameserver 10.0.0.2\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := os.Lstat(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if st.Mode()&os.ModeSymlink == 0 {
+		t.Error("symlink replaced by a file")
+	}
+
+	if got := readFile(t, target); got != "nameserver 10.0.0.2\n" {
+		t.Errorf("target content '%s'", got)
+	}
+
+	noTempFiles(t, dir, 2)
+}
+
+func TestWriteConfigFileBackups(t *testing.T) {
+	testBackups(t, 2)
+
+	path := filepath.Join(t.TempDir(), "sysctl.conf")
+
+	for _, content := range []string{"v1\n", "v2\n", "v3\n", "v4\n"} {
+		_, _, err := writeConfigFile(path, []byte(content), 0644, "test", "write")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := BackupFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(backups) != 2 {
+		t.Fatalf("%d backups, want 2: %v", len(backups), backups)
+	}
+
+	// the oldest are dropped, the last backup is the replaced version
+	if readFile(t, backups[0]) != "v2\n" || readFile(t, backups[1]) != "v3\n" {
+		t.Errorf("backups '%s' and '%s', want v2 and v3", readFile(t, backups[0]), readFile(t, backups[1]))
+	}
+
+	if got := readFile(t, path); got != "v4\n" {
+		t.Errorf("content '%s'", got)
+	}
+}
+
+func TestWriteConfigFileNoBackups(t *testing.T) {
+	dir := testBackups(t, 0)
+
+	path := filepath.Join(t.TempDir(), "system.conf")
+
+	for _, content := range []string{"v1\n", "v2\n"} {
+		_, _, err := writeConfigFile(path, []byte(content), 0644, "test", "write")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := os.Stat(filepath.Join(dir, "backup"))
+	if !os.IsNotExist(err) {
+		t.Errorf("Keep=0 created the backup dir: %v", err)
+	}
+}
@@ -3,8 +3,10 @@
 package share
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/godbus/dbus"
 )
@@ -32,3 +34,59 @@ func GetSystemBusPrivateConn() (*dbus.Conn, error) {
 
 	return conn, nil
 }
+
+//PingSystemBus checks the bus, or the peer name on it when given, answers
+//within timeout. A bus activatable name nobody owns yet counts as reachable
+//and is not started by the check
+func PingSystemBus(name string, timeout time.Duration) error {
+	conn, err := GetSystemBusPrivateConn()
+	if err != nil {
+		return err
+	}
+	if conn == nil {
+		return fmt.Errorf("Failed to say hello to the system bus")
+	}
+	defer conn.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pingName(conn, name)
+	}()
+
+	select {
+	case err = <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("No reply within %s", timeout)
+	}
+}
+
+func pingName(conn *dbus.Conn, name string) error {
+	if name == "" {
+		return conn.BusObject().Call("org.freedesktop.DBus.Peer.Ping", 0).Err
+	}
+
+	var owned bool
+	err := conn.BusObject().Call("org.freedesktop.DBus.NameHasOwner", 0, name).Store(&owned)
+	if err != nil {
+		return err
+	}
+
+	if owned {
+		return conn.Object(name, "/").Call("org.freedesktop.DBus.Peer.Ping", 0).Err
+	}
+
+	var activatable []string
+	err = conn.BusObject().Call("org.freedesktop.DBus.ListActivatableNames", 0).Store(&activatable)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range activatable {
+		if n == name {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("'%s' is not running", name)
+}
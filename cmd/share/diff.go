@@ -0,0 +1,199 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	diffContext = 3
+
+	// largest LCS table of the changed lines, 8 MB. Bigger changes are
+	// shown as the old lines replaced by the new ones
+	maxDiffCells = 1 << 20
+)
+
+// one line of an edit script: ' ' kept, '-' removed, '+' added
+type diffLine struct {
+	op   byte
+	text string
+	a, b int
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// editScript an edit script from a to b. Common leading and trailing lines
+// are kept, the lines between are matched by longest common subsequence when
+// its table fits in maxDiffCells
+func editScript(a []string, b []string) []diffLine {
+	var script []diffLine
+
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		script = append(script, diffLine{' ', a[pre], pre, pre})
+		pre++
+	}
+
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+
+	n, m := len(a)-pre-suf, len(b)-pre-suf
+
+	var middle []diffLine
+	if n > 0 && m > 0 && n*m > maxDiffCells {
+		middle = replaceScript(a[pre:pre+n], b[pre:pre+m])
+	} else {
+		middle = lcsScript(a[pre:pre+n], b[pre:pre+m])
+	}
+
+	for _, l := range middle {
+		script = append(script, diffLine{l.op, l.text, l.a + pre, l.b + pre})
+	}
+
+	for k := 0; k < suf; k++ {
+		script = append(script, diffLine{' ', a[pre+n+k], pre + n + k, pre + m + k})
+	}
+
+	return script
+}
+
+// replaceScript removes all of a and adds all of b
+func replaceScript(a []string, b []string) []diffLine {
+	script := make([]diffLine, 0, len(a)+len(b))
+
+	for i := range a {
+		script = append(script, diffLine{'-', a[i], i, 0})
+	}
+
+	for j := range b {
+		script = append(script, diffLine{'+', b[j], len(a), j})
+	}
+
+	return script
+}
+
+// lcsScript the shortest edit script from a to b by longest common subsequence
+func lcsScript(a []string, b []string) []diffLine {
+	n, m := len(a), len(b)
+
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var script []diffLine
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			script = append(script, diffLine{' ', a[i], i, j})
+			i++
+			j++
+		case j < m && (i == n || lcs[i][j+1] > lcs[i+1][j]):
+			script = append(script, diffLine{'+', b[j], i, j})
+			j++
+		default:
+			script = append(script, diffLine{'-', a[i], i, j})
+			i++
+		}
+	}
+
+	return script
+}
+
+func hunkRange(start int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+//UnifiedDiff the changes from a to b in unified format, empty when equal
+func UnifiedDiff(fromName string, toName string, a string, b string) string {
+	script := editScript(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	for i := 0; i < len(script); {
+		if script[i].op == ' ' {
+			i++
+			continue
+		}
+
+		// grow the hunk while changes are closer than twice the context
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+
+		end := i
+		for k := i; k < len(script) && k-end <= 2*diffContext; k++ {
+			if script[k].op != ' ' {
+				end = k
+			}
+		}
+
+		stop := end + diffContext + 1
+		if stop > len(script) {
+			stop = len(script)
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+		}
+
+		var na, nb int
+		for _, l := range script[start:stop] {
+			if l.op != '+' {
+				na++
+			}
+			if l.op != '-' {
+				nb++
+			}
+		}
+
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(script[start].a, na), hunkRange(script[start].b, nb))
+		for _, l := range script[start:stop] {
+			out.WriteByte(l.op)
+			out.WriteString(l.text)
+			if !strings.HasSuffix(l.text, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = stop
+	}
+
+	return out.String()
+}
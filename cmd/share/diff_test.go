@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "[Match]\nName=eth0\n\n[Network]\nDHCP=yes\n"
+	b := "[Match]\nName=eth0\n\n[Network]\nAddress=10.0.0.2/24\n"
+
+	want := "--- a\n+++ b\n@@ -2,4 +2,4 @@\n Name=eth0\n \n [Network]\n-DHCP=yes\n+Address=10.0.0.2/24\n"
+	if got := UnifiedDiff("a", "b", a, b); got != want {
+		t.Errorf("diff\n%s\nwant\n%s", got, want)
+	}
+
+	if got := UnifiedDiff("a", "b", a, a); got != "" {
+		t.Errorf("diff of equal files '%s'", got)
+	}
+}
+
+func TestUnifiedDiffLargeFiles(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&a, "old %d\n", i)
+		fmt.Fprintf(&b, "new %d\n", i)
+	}
+
+	head := "# generated\n"
+	d := UnifiedDiff("a", "b", head+a.String()+"end\n", head+b.String()+"end\n")
+
+	// too large for the LCS table, the changed lines are replaced as a whole
+	if !strings.HasPrefix(d, "--- a\n+++ b\n@@ -1,3002 +1,3002 @@\n # generated\n-old 0\n") {
+		t.Errorf("unexpected diff start '%.80s'", d)
+	}
+
+	if strings.Count(d, "\n-old ") != 3000 || strings.Count(d, "\n+new ") != 3000 {
+		t.Error("not every changed line in the diff")
+	}
+
+	if !strings.HasSuffix(d, "+new 2999\n end\n") {
+		t.Errorf("unexpected diff end '%s'", d[len(d)-40:])
+	}
+}
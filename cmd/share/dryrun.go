@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+type dryRunKey struct{}
+
+//FileChange a file a dry run would have written, or removed
+type FileChange struct {
+	Path    string `json:"path"`
+	Exists  bool   `json:"exists"`
+	Removed bool   `json:"removed,omitempty"`
+	Content string `json:"content"`
+	Diff    string `json:"diff"`
+}
+
+//Preview what a dry run request would have changed
+type Preview struct {
+	DryRun bool         `json:"dry_run"`
+	Files  []FileChange `json:"files"`
+	lock   sync.Mutex
+}
+
+//ParseDryRun the ?dry_run= flag of a request
+func ParseDryRun(r *http.Request) (bool, error) {
+	v := r.URL.Query().Get("dry_run")
+	if v == "" {
+		return false, nil
+	}
+
+	dry, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, NewError(http.StatusBadRequest, "Invalid dry_run '%s'", v)
+	}
+
+	return dry, nil
+}
+
+//WithDryRun marks the request as a dry run recording its writes in p
+func WithDryRun(r *http.Request, p *Preview) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), dryRunKey{}, p))
+}
+
+//DryRun the preview of a dry run request or nil
+func DryRun(r *http.Request) *Preview {
+	p, _ := r.Context().Value(dryRunKey{}).(*Preview)
+
+	return p
+}
+
+//IsDryRun whether the request must not change anything
+func IsDryRun(r *http.Request) bool {
+	return DryRun(r) != nil
+}
+
+//Add records content to be written to path with its diff against the
+//current file
+func (p *Preview) Add(path string, content string) error {
+	old, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	from := path
+	if err != nil {
+		from = "/dev/null"
+	}
+
+	c := FileChange{
+		Path:    path,
+		Exists:  err == nil,
+		Content: content,
+		Diff:    UnifiedDiff(from, path, string(old), content),
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.Files = append(p.Files, c)
+
+	return nil
+}
+
+//Remove records the removal of path with its diff against the current file
+func (p *Preview) Remove(path string) error {
+	old, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	c := FileChange{
+		Path:    path,
+		Exists:  err == nil,
+		Removed: true,
+		Diff:    UnifiedDiff(path, "/dev/null", string(old), ""),
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.Files = append(p.Files, c)
+
+	return nil
+}
+
+//RenderLines the content WriteFullFile writes for lines
+func RenderLines(lines []string) string {
+	var content string
+	for _, line := range lines {
+		content += line + "\n"
+	}
+
+	return content
+}
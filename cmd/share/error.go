@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/godbus/dbus"
+	"github.com/vishvananda/netlink"
+)
+
+// D-Bus errors of unknown objects
+var dbusNotFoundErrors = []string{
+	"org.freedesktop.systemd1.NoSuchUnit",
+	"org.freedesktop.machine1.NoSuchMachine",
+	"org.freedesktop.machine1.NoSuchImage",
+	"org.freedesktop.login1.NoSuchSession",
+	"org.freedesktop.login1.NoSuchUser",
+	"org.freedesktop.DBus.Error.UnknownObject",
+}
+
+// firewalld exception codes of unknown zones, services and the like
+var firewalldNotFoundErrors = []string{
+	"INVALID_ZONE",
+	"INVALID_SERVICE",
+	"INVALID_ICMPTYPE",
+	"INVALID_IPSET",
+	"INVALID_HELPER",
+}
+
+//Error JSON error envelope replied by all routers
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+//NewError an error with a HTTP status code
+func NewError(code int, format string, a ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, a...),
+	}
+}
+
+func wrapError(code int, err error) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+
+	return &Error{
+		Code:    code,
+		Message: err.Error(),
+	}
+}
+
+//BadRequest malformed or invalid input
+func BadRequest(err error) *Error {
+	return wrapError(http.StatusBadRequest, err)
+}
+
+//NotFound unknown unit, link, zone, user ...
+func NotFound(err error) *Error {
+	return wrapError(http.StatusNotFound, err)
+}
+
+//Conflict the object already exists
+func Conflict(err error) *Error {
+	return wrapError(http.StatusConflict, err)
+}
+
+func dbusErrorCode(e *dbus.Error) int {
+	for _, n := range dbusNotFoundErrors {
+		if e.Name == n {
+			return http.StatusNotFound
+		}
+	}
+
+	for _, n := range firewalldNotFoundErrors {
+		if strings.HasPrefix(e.Error(), n) {
+			return http.StatusNotFound
+		}
+	}
+
+	return http.StatusInternalServerError
+}
+
+// errorCode the HTTP status code of well known errors from the libraries we use
+func errorCode(err error) int {
+	switch e := err.(type) {
+	case *Error:
+		return e.Code
+	case netlink.LinkNotFoundError:
+		return http.StatusNotFound
+	case *json.SyntaxError, *json.UnmarshalTypeError:
+		return http.StatusBadRequest
+	case dbus.Error:
+		return dbusErrorCode(&e)
+	case *dbus.Error:
+		return dbusErrorCode(e)
+	}
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
+
+//HTTPError reply err as a JSON error envelope. The status code is taken from
+//an *Error or from well known library errors and is 500 otherwise
+func HTTPError(rw http.ResponseWriter, err error) {
+	e := wrapError(errorCode(err), err)
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	rw.WriteHeader(e.Code)
+
+	json.NewEncoder(rw).Encode(e)
+}
+
+//MethodNotAllowed reply 405 for a method the route does not handle
+func MethodNotAllowed(rw http.ResponseWriter, r *http.Request) {
+	HTTPError(rw, NewError(http.StatusMethodNotAllowed, "Method %s not allowed on %s", r.Method, r.URL.Path))
+}
+
+//RouteNotFound reply 404 for an unknown route
+func RouteNotFound(rw http.ResponseWriter, r *http.Request) {
+	HTTPError(rw, NewError(http.StatusNotFound, "No route for %s", r.URL.Path))
+}
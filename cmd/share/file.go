@@ -9,6 +9,16 @@ import (
 	"strings"
 )
 
+//IsValidFileName tests whether name is a plain file name, so joined to a
+//directory it can not point outside of it
+func IsValidFileName(name string) bool {
+	if name == "" || name == "." || strings.Contains(name, "..") {
+		return false
+	}
+
+	return !strings.ContainsAny(name, "/\x00")
+}
+
 //PathExists test if path exists
 func PathExists(path string) bool {
 	_, r := os.Stat(path)
@@ -42,22 +52,12 @@ func ReadFullFile(path string) ([]string, error) {
 	return lines, nil
 }
 
-//WriteFullFile write a string arrray to a file
+//WriteFullFile write a string arrray to a configuration file atomically
+//keeping a backup, see WriteConfigFile
 func WriteFullFile(path string, lines []string) error {
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	w := bufio.NewWriter(file)
-	for _, line := range lines {
-		fmt.Fprintln(w, line)
-	}
+	_, _, err := writeConfigFile(path, []byte(RenderLines(lines)), 0644, "", "")
 
-	w.Flush()
-
-	return nil
+	return err
 }
 
 //ReadOneLineFile read one line from a file
@@ -78,18 +78,23 @@ func ReadOneLineFile(path string) (string, error) {
 	return line, nil
 }
 
-//WriteOneLineFile write oneline to a file
+//WriteOneLineFile write oneline to a file in place. Meant for /proc and /sys
+//which can not be replaced by a rename, configuration goes to WriteConfigFile
 func WriteOneLineFile(path string, line string) error {
 	file, err := os.Create(path)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
 	w := bufio.NewWriter(file)
 	fmt.Fprintln(w, line)
 
-	return w.Flush()
+	err = w.Flush()
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
 }
 
 //CreateDirectory creates a dir
@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"testing"
+)
+
+func TestIsValidFileName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"eth0", true},
+		{"br-lan.100", true},
+		{"", false},
+		{".", false},
+		{"..", false},
+		{"../../../etc/cron.d/x", false},
+		{"eth0/..", false},
+		{"a..b", false},
+		{"eth0\x00", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsValidFileName(tt.name); got != tt.valid {
+			t.Errorf("IsValidFileName('%s') = %v, want %v", tt.name, got, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHistoryDir  = "/var/lib/api-routerd/history"
+	defaultHistoryKeep = 100
+
+	historySuffix = ".history"
+	historyIndex  = "index.json"
+
+	// operations of revisions not written by a request
+	operationInitial  = "initial"
+	operationExternal = "external change"
+)
+
+//HistoryConfig the [History] section of the conf file. The last Keep
+//revisions of every file written by api-routerd are kept below Dir
+type HistoryConfig struct {
+	Dir  string
+	Keep int
+}
+
+//Revision one version of a configuration file. Author is empty for versions
+//not written by api-routerd, Deleted set when the file was removed
+type Revision struct {
+	ID        int       `json:"id"`
+	Path      string    `json:"path"`
+	Author    string    `json:"author"`
+	Operation string    `json:"operation"`
+	Time      time.Time `json:"time"`
+	Size      int       `json:"size"`
+	Deleted   bool      `json:"deleted,omitempty"`
+}
+
+var history = struct {
+	sync.RWMutex
+	conf HistoryConfig
+}{conf: DefaultHistoryConfig()}
+
+//DefaultHistoryConfig keep 100 revisions below /var/lib/api-routerd/history
+func DefaultHistoryConfig() HistoryConfig {
+	return HistoryConfig{
+		Dir:  defaultHistoryDir,
+		Keep: defaultHistoryKeep,
+	}
+}
+
+//SetHistoryConfig sets where and how many revisions are kept
+func SetHistoryConfig(c HistoryConfig) {
+	history.Lock()
+	defer history.Unlock()
+
+	history.conf = c
+}
+
+func historyConfig() HistoryConfig {
+	history.RLock()
+	defer history.RUnlock()
+
+	return history.conf
+}
+
+// historyDir the revisions of path, one file per revision and an index
+func historyDir(path string) string {
+	return filepath.Join(historyConfig().Dir, filepath.Clean(path)) + historySuffix
+}
+
+func loadRevisions(dir string) ([]Revision, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, historyIndex))
+	if err != nil {
+		return nil, err
+	}
+
+	var revs []Revision
+	err = json.Unmarshal(b, &revs)
+	if err != nil {
+		return nil, err
+	}
+
+	return revs, nil
+}
+
+func addRevision(dir string, revs []Revision, path string, content []byte, deleted bool, author string, operation string) ([]Revision, error) {
+	rev := Revision{
+		ID:        1,
+		Path:      path,
+		Author:    author,
+		Operation: operation,
+		Time:      time.Now().UTC(),
+		Size:      len(content),
+		Deleted:   deleted,
+	}
+
+	if len(revs) > 0 {
+		rev.ID = revs[len(revs)-1].ID + 1
+	}
+
+	err := WriteFileAtomic(filepath.Join(dir, strconv.Itoa(rev.ID)), content, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(revs, rev), nil
+}
+
+// recordRevision adds content to the history of path, or its removal when
+// deleted. old is the file it replaced, nil for a new file. A file never
+// seen before or changed behind our back gets a revision without author
+// first. It returns the new revision, nil when the history is off. Caller
+// holds the file lock
+func recordRevision(path string, old []byte, content []byte, deleted bool, author string, operation string) (*Revision, error) {
+	c := historyConfig()
+	if c.Keep <= 0 {
+		return nil, nil
+	}
+
+	dir := historyDir(path)
+
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	revs, err := loadRevisions(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if old != nil {
+		if len(revs) == 0 {
+			revs, err = addRevision(dir, revs, path, old, false, "", operationInitial)
+		} else if last, rerr := ioutil.ReadFile(filepath.Join(dir, strconv.Itoa(revs[len(revs)-1].ID))); rerr != nil || revs[len(revs)-1].Deleted || !bytes.Equal(last, old) {
+			revs, err = addRevision(dir, revs, path, old, false, "", operationExternal)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	revs, err = addRevision(dir, revs, path, content, deleted, author, operation)
+	if err != nil {
+		return nil, err
+	}
+
+	rev := revs[len(revs)-1]
+
+	for len(revs) > c.Keep {
+		os.Remove(filepath.Join(dir, strconv.Itoa(revs[0].ID)))
+		revs = revs[1:]
+	}
+
+	b, err := json.MarshalIndent(revs, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	err = WriteFileAtomic(filepath.Join(dir, historyIndex), b, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rev, nil
+}
+
+// requestAuthor the user a request was made by
+func requestAuthor(r *http.Request) string {
+	if id := RequestIdentity(r); id != nil {
+		return id.User
+	}
+
+	return ""
+}
+
+//Revisions the history of a file written by api-routerd, oldest first
+func Revisions(path string) ([]Revision, error) {
+	if !filepath.IsAbs(path) {
+		return nil, NewError(http.StatusBadRequest, "Path '%s' must be absolute", path)
+	}
+
+	revs, err := loadRevisions(historyDir(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, NewError(http.StatusNotFound, "No history of '%s'", path)
+		}
+
+		return nil, err
+	}
+
+	return revs, nil
+}
+
+//RevisionContent a revision of a file and its content
+func RevisionContent(path string, id int) (*Revision, []byte, error) {
+	revs, err := Revisions(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range revs {
+		if revs[i].ID == id {
+			b, err := ioutil.ReadFile(filepath.Join(historyDir(path), strconv.Itoa(id)))
+			if err != nil {
+				return nil, nil, err
+			}
+
+			return &revs[i], b, nil
+		}
+	}
+
+	return nil, nil, NewError(http.StatusNotFound, "No revision %d of '%s'", id, path)
+}
+
+//HistoryFiles the files with a history
+func HistoryFiles() ([]string, error) {
+	root := historyConfig().Dir
+
+	var files []string
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == root {
+				return filepath.SkipDir
+			}
+
+			return err
+		}
+
+		if info.IsDir() && strings.HasSuffix(p, historySuffix) {
+			if PathExists(filepath.Join(p, historyIndex)) {
+				files = append(files, strings.TrimSuffix(strings.TrimPrefix(p, root), historySuffix))
+			}
+
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+//RestoreConfigFile writes content back outside of a request, for rollbacks.
+//It is backed up and recorded like WriteConfigFile
+func RestoreConfigFile(path string, content []byte, author string, operation string) error {
+	_, _, err := writeConfigFile(path, content, 0644, author, operation)
+
+	return err
+}
+
+//RevertConfigFile writes a revision of a file back, or removes the file for
+//a deletion. The revert is a new revision so it can be undone as well. It
+//returns that revision, nil for a dry run or when the history is off
+func RevertConfigFile(r *http.Request, path string, id int) (*Revision, error) {
+	target, content, err := RevisionContent(path, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if p := DryRun(r); p != nil {
+		if target.Deleted {
+			return nil, p.Remove(path)
+		}
+
+		return nil, p.Add(path, string(content))
+	}
+
+	operation := fmt.Sprintf("revert to %d", id)
+
+	if target.Deleted {
+		old, rev, err := removeConfigFile(path, requestAuthor(r), operation)
+		if err != nil {
+			return nil, err
+		}
+
+		if old != nil {
+			undoConfigFile(r, path, old, 0644, operation)
+		}
+
+		return rev, nil
+	}
+
+	old, rev, err := writeConfigFile(path, content, 0644, requestAuthor(r), operation)
+	if err != nil {
+		return nil, err
+	}
+
+	undoConfigFile(r, path, old, 0644, operation)
+
+	return rev, nil
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testHistory(t *testing.T, keep int) {
+	dir := t.TempDir()
+
+	SetBackupConfig(BackupConfig{Dir: filepath.Join(dir, "backup"), Keep: 5})
+	SetHistoryConfig(HistoryConfig{Dir: filepath.Join(dir, "history"), Keep: keep})
+
+	t.Cleanup(func() {
+		SetBackupConfig(DefaultBackupConfig())
+		SetHistoryConfig(DefaultHistoryConfig())
+	})
+}
+
+func TestRemoveConfigFileRecordsDeletion(t *testing.T) {
+	testHistory(t, 10)
+
+	path := filepath.Join(t.TempDir(), "10-br0.netdev")
+
+	_, _, err := writeConfigFile(path, []byte("[NetDev]\nName=br0\n"), 0644, "max", "create")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, rev, err := removeConfigFile(path, "max", "delete")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(old) != "[NetDev]\nName=br0\n" {
+		t.Errorf("removed content '%s'", old)
+	}
+
+	if rev == nil || !rev.Deleted || rev.ID != 2 || rev.Author != "max" {
+		t.Fatalf("deletion revision %+v", rev)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file not removed: %v", err)
+	}
+
+	backups, err := BackupFiles(path)
+	if err != nil || len(backups) != 1 {
+		t.Errorf("backups %v: %v", backups, err)
+	}
+
+	// reverting to the created revision brings the file back
+	rev, err = RevertConfigFile(httptest.NewRequest("POST", "/api/config/revert", nil), path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rev == nil || rev.ID != 3 || rev.Deleted {
+		t.Errorf("revert revision %+v", rev)
+	}
+
+	if got := readFile(t, path); got != "[NetDev]\nName=br0\n" {
+		t.Errorf("reverted content '%s'", got)
+	}
+
+	// and reverting to the deletion removes it again
+	rev, err = RevertConfigFile(httptest.NewRequest("POST", "/api/config/revert", nil), path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rev == nil || rev.ID != 4 || !rev.Deleted {
+		t.Errorf("revert revision %+v", rev)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file not removed: %v", err)
+	}
+}
+
+func TestRevertConfigFileHistoryOff(t *testing.T) {
+	testHistory(t, 10)
+
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+
+	for _, content := range []string{"nameserver 10.0.0.1\n", "nameserver 10.0.0.2\n"} {
+		_, _, err := writeConfigFile(path, []byte(content), 0644, "max", "write")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	SetHistoryConfig(HistoryConfig{Dir: historyConfig().Dir, Keep: 0})
+
+	rev, err := RevertConfigFile(httptest.NewRequest("POST", "/api/config/revert", nil), path, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rev != nil {
+		t.Errorf("revert recorded %+v with the history off", rev)
+	}
+
+	if got := readFile(t, path); got != "nameserver 10.0.0.1\n" {
+		t.Errorf("reverted content '%s'", got)
+	}
+}
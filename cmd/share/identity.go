@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"context"
+	"net/http"
+)
+
+type identityKey struct{}
+
+//Identity the authenticated caller of a request
+type Identity struct {
+	User string `json:"user"`
+	Role string `json:"role"`
+}
+
+//WithIdentity attach the authenticated caller to the request
+func WithIdentity(r *http.Request, id *Identity) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), identityKey{}, id))
+}
+
+//RequestIdentity the authenticated caller of the request or nil
+func RequestIdentity(r *http.Request) *Identity {
+	id, _ := r.Context().Value(identityKey{}).(*Identity)
+
+	return id
+}
@@ -7,14 +7,19 @@ import (
 
 //JSONResponse form a JSON respose from a interface and send
 func JSONResponse(response interface{}, w http.ResponseWriter) error {
+	return JSONResponseStatus(response, http.StatusOK, w)
+}
+
+//JSONResponseStatus form a JSON respose from a interface and send with status
+func JSONResponseStatus(response interface{}, status int, w http.ResponseWriter) error {
 	json, err := json.Marshal(response)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		HTTPError(w, err)
 		return err
 	}
 
-	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
 	w.Write(json)
 
 	return nil
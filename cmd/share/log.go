@@ -3,8 +3,11 @@
 package share
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -15,13 +18,109 @@ const (
 	defaultLogFile = "api-router.log"
 )
 
-//InitLog inits the logger
-func InitLog() error {
-	log := logrus.New()
+//LogConfig the [Log] section of the conf file
+type LogConfig struct {
+	Level  string
+	Format string
+	Dir    string
+
+	// rotate the log file once it grows beyond MaxSize MB and keep MaxBackups old files
+	MaxSize    int64
+	MaxBackups int
+}
+
+// rotateWriter a log file rotated by size
+type rotateWriter struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	size       int64
+	file       *os.File
+	lock       sync.Mutex
+}
+
+func newRotateWriter(path string, maxSize int64, maxBackups int) (*rotateWriter, error) {
+	w := &rotateWriter{
+		path:       path,
+		maxSize:    maxSize * 1024 * 1024,
+		maxBackups: maxBackups,
+	}
+
+	err := w.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *rotateWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.file = f
+	w.size = st.Size()
+
+	return nil
+}
+
+// rotate shifts api-router.log.N to api-router.log.N+1 and drops the oldest
+func (w *rotateWriter) rotate() error {
+	w.file.Close()
+
+	if w.maxBackups <= 0 {
+		os.Remove(w.path)
+	} else {
+		os.Remove(fmt.Sprintf("%s.%d", w.path, w.maxBackups))
+
+		for i := w.maxBackups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+		}
+
+		os.Rename(w.path, w.path+".1")
+	}
+
+	return w.open()
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.maxSize > 0 && w.size+int64(len(p)) > w.maxSize {
+		err := w.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+//InitLog inits the logger. The API_ROUTERD_LOG_LEVEL, API_ROUTERD_LOG_FORMAT
+//and API_ROUTERD_LOG_DIR environment variables override the conf file.
+func InitLog(c *LogConfig) error {
+	log := logrus.StandardLogger()
 	log.Level = logrus.InfoLevel
 
 	viper.AutomaticEnv()
-	lvl := viper.GetString("API_ROUTERD_LOG_LEVEL")
+
+	lvl := c.Level
+	if v := viper.GetString("API_ROUTERD_LOG_LEVEL"); v != "" {
+		lvl = v
+	}
+
 	if lvl != "" {
 		l, err := logrus.ParseLevel(lvl)
 		if err != nil {
@@ -31,33 +130,41 @@ func InitLog() error {
 		}
 	}
 
-	switch viper.GetString("API_ROUTERD_LOG_FORMAT") {
+	format := c.Format
+	if v := viper.GetString("API_ROUTERD_LOG_FORMAT"); v != "" {
+		format = v
+	}
+
+	switch format {
 	case "json":
 		log.SetFormatter(&logrus.JSONFormatter{})
 	default:
-	case "text":
 		log.SetFormatter(&logrus.TextFormatter{})
 	}
 
-	logDir := viper.GetString("API_ROUTERD_LOG_DIR")
+	logDir := c.Dir
+	if v := viper.GetString("API_ROUTERD_LOG_DIR"); v != "" {
+		logDir = v
+	}
 	if logDir == "" {
 		logDir = defaultLogDir
 	}
 
-	err := CreateDirectory(logDir, 0644)
+	err := CreateDirectoryNested(logDir, 0755)
 	if err != nil {
 		log.Errorf("Failed to create log directory. path: %s, err: %s", logDir, err)
 		return err
 	}
 
 	logFile := path.Join(logDir, defaultLogFile)
-	f, err := os.OpenFile(logFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	w, err := newRotateWriter(logFile, c.MaxSize, c.MaxBackups)
 	if err != nil {
 		log.Errorf("Failed to create log file. path: %s, err: %s", logFile, err)
 		return err
 	}
 
-	log.SetOutput(f)
+	// keep stderr so the journal still gets the log under systemd
+	log.SetOutput(io.MultiWriter(os.Stderr, w))
 	log.SetReportCaller(true)
 	log.Info("Starting API Router")
 
@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+//Operation one method of a route as published in the OpenAPI document.
+//Request and Response are zero values of the JSON bodies, nil for none.
+//Versions limits the operation to some API versions, empty for all.
+//DryRun operations honour ?dry_run=true, Confirm ones ?confirm_timeout=
+type Operation struct {
+	Method   string
+	Summary  string
+	Request  interface{}
+	Response interface{}
+	Versions []string
+	DryRun   bool
+	Confirm  bool
+}
+
+// operations declared per route by the Register* functions
+var operations = struct {
+	sync.RWMutex
+	m map[*mux.Route][]Operation
+}{m: make(map[*mux.Route][]Operation)}
+
+//Op shorthand to declare an Operation
+func Op(method string, summary string, request interface{}, response interface{}) Operation {
+	return Operation{
+		Method:   method,
+		Summary:  summary,
+		Request:  request,
+		Response: response,
+	}
+}
+
+//For limits the operation to the given API versions
+func (o Operation) For(versions ...string) Operation {
+	o.Versions = versions
+
+	return o
+}
+
+//Previewable marks the operation as supporting dry runs
+func (o Operation) Previewable() Operation {
+	o.DryRun = true
+
+	return o
+}
+
+//Confirmable marks a network operation as supporting commit-confirm
+func (o Operation) Confirmable() Operation {
+	o.Confirm = true
+
+	return o
+}
+
+//ServedIn whether the operation is served under the API version
+func (o Operation) ServedIn(version string) bool {
+	if len(o.Versions) == 0 {
+		return true
+	}
+
+	for _, v := range o.Versions {
+		if v == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+//Describe declares the operations a route serves
+func Describe(route *mux.Route, ops ...Operation) *mux.Route {
+	operations.Lock()
+	defer operations.Unlock()
+
+	operations.m[route] = append(operations.m[route], ops...)
+
+	return route
+}
+
+//RequestOperation the operation a request was routed to or nil
+func RequestOperation(r *http.Request) *Operation {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return nil
+	}
+
+	for _, op := range RouteOperations(route) {
+		if op.Method == r.Method {
+			return &op
+		}
+	}
+
+	return nil
+}
+
+//RouteOperations the operations declared for a route
+func RouteOperations(route *mux.Route) []Operation {
+	operations.RLock()
+	defer operations.RUnlock()
+
+	return operations.m[route]
+}
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	//StreamKeepalive how often an idle stream is pinged
+	StreamKeepalive = 30 * time.Second
+)
+
+// closed on shutdown so long lived streams do not hold the server
+var streamsStopped = make(chan struct{})
+var streamsStopOnce sync.Once
+
+//StopStreams ends every open event stream
+func StopStreams() {
+	streamsStopOnce.Do(func() {
+		close(streamsStopped)
+	})
+}
+
+//EventStream pushes JSON events over Server-Sent Events or, when the
+//request asks for an upgrade, over a WebSocket
+type EventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	ws      *webSocket
+
+	done chan struct{}
+	once sync.Once
+}
+
+//NewEventStream start streaming the response of r
+func NewEventStream(w http.ResponseWriter, r *http.Request) (*EventStream, error) {
+	s := &EventStream{
+		w:    w,
+		done: make(chan struct{}),
+	}
+
+	var gone <-chan struct{}
+
+	if IsWebSocketUpgrade(r) {
+		ws, err := upgradeWebSocket(w, r)
+		if err != nil {
+			return nil, err
+		}
+
+		s.ws = ws
+		gone = ws.Done()
+	} else {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			return nil, errors.New("Connection does not support streaming")
+		}
+
+		s.flusher = f
+		gone = r.Context().Done()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		f.Flush()
+	}
+
+	go func() {
+		select {
+		case <-gone:
+		case <-streamsStopped:
+		case <-s.done:
+		}
+
+		s.Close()
+	}()
+
+	return s, nil
+}
+
+//Send one event. With SSE event names the event, a WebSocket message
+//carries the JSON only
+func (s *EventStream) Send(event string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if s.ws != nil {
+		return s.ws.WriteText(b)
+	}
+
+	select {
+	case <-s.done:
+		return errors.New("Stream closed")
+	default:
+	}
+
+	_, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, b)
+	if err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+
+	return nil
+}
+
+//Ping keeps proxies from timing out an idle stream
+func (s *EventStream) Ping() error {
+	if s.ws != nil {
+		return s.ws.Ping()
+	}
+
+	_, err := fmt.Fprint(s.w, ": ping\n\n")
+	if err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+
+	return nil
+}
+
+//Done closed once the client went away or the server shuts down
+func (s *EventStream) Done() <-chan struct{} {
+	return s.done
+}
+
+//Close ends the stream
+func (s *EventStream) Close() {
+	s.once.Do(func() {
+		close(s.done)
+
+		if s.ws != nil {
+			s.ws.Close()
+		}
+	})
+}
@@ -2,6 +2,7 @@ package share
 
 import (
 	"errors"
+	"sort"
 )
 
 //StringContains looks for a string in a string array
@@ -35,3 +36,14 @@ func StringDeleteSlice(list []string, s string) ([]string, error) {
 
 	return nil, errors.New("Slice not found")
 }
+
+//SortedKeys the keys of a map in order, to render files the same every time
+func SortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
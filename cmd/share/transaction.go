@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+type transactionKey struct{}
+
+type undo struct {
+	description string
+	fn          func() error
+}
+
+//Transaction collects how to undo the changes of a request. Handlers
+//register a compensation with OnRollback after each change they make
+type Transaction struct {
+	lock sync.Mutex
+	undo []undo
+}
+
+//WithTransaction records the compensations of the request's changes in t
+func WithTransaction(r *http.Request, t *Transaction) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), transactionKey{}, t))
+}
+
+//InTransaction the transaction of a request or nil
+func InTransaction(r *http.Request) *Transaction {
+	t, _ := r.Context().Value(transactionKey{}).(*Transaction)
+
+	return t
+}
+
+//OnRollback registers fn to undo a change made by the request. It does
+//nothing outside of a transaction
+func OnRollback(r *http.Request, description string, fn func() error) {
+	t := InTransaction(r)
+	if t == nil {
+		return
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.undo = append(t.undo, undo{description: description, fn: fn})
+}
+
+//Compensations the descriptions of the registered compensations in the
+//order they were made
+func (t *Transaction) Compensations() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	d := make([]string, 0, len(t.undo))
+	for _, u := range t.undo {
+		d = append(d, u.description)
+	}
+
+	return d
+}
+
+//Rollback runs the compensations, last change first. It carries on after
+//a failed one and returns all errors
+func (t *Transaction) Rollback() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var errs []string
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		err := t.undo[i].fn()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", t.undo[i].description, err))
+		}
+	}
+	t.undo = nil
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// undoConfigFile registers putting back old, the content path had before
+// the request wrote it, or removing path when it did not exist
+func undoConfigFile(r *http.Request, path string, old []byte, perm os.FileMode, operation string) {
+	if old == nil {
+		OnRollback(r, "remove "+path, func() error {
+			_, _, err := removeConfigFile(path, requestAuthor(r), "rollback of "+operation)
+			return err
+		})
+
+		return
+	}
+
+	OnRollback(r, "restore "+path, func() error {
+		_, _, err := writeConfigFile(path, old, perm, requestAuthor(r), "rollback of "+operation)
+		return err
+	})
+}
+
+//SetOneLineFile writes line to a /proc or /sys file like WriteOneLineFile
+//and registers writing the previous value back
+func SetOneLineFile(r *http.Request, path string, line string) error {
+	old, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = WriteOneLineFile(path, line)
+	if err != nil {
+		return err
+	}
+
+	OnRollback(r, "reset "+path, func() error {
+		return WriteOneLineFile(path, strings.TrimSuffix(string(old), "\n"))
+	})
+
+	return nil
+}
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"context"
+	"net/http"
+)
+
+// API versions the modules are mounted under
+const (
+	APIv1 = "v1"
+	APIv2 = "v2"
+)
+
+// APIVersions all versions in the order they are mounted
+var APIVersions = []string{APIv1, APIv2}
+
+type versionKey struct{}
+
+//WithAPIVersion attach the API version the request was routed under
+func WithAPIVersion(r *http.Request, version string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), versionKey{}, version))
+}
+
+//IsAPIVersion whether v names one of the mounted API versions
+func IsAPIVersion(v string) bool {
+	for _, version := range APIVersions {
+		if v == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+//APIVersion the API version of the request. Unversioned legacy paths are v1
+func APIVersion(r *http.Request) string {
+	v, ok := r.Context().Value(versionKey{}).(string)
+	if !ok {
+		return APIv1
+	}
+
+	return v
+}
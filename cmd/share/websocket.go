@@ -0,0 +1,310 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RFC 6455
+const (
+	websocketGUID    = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	websocketVersion = "13"
+
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xa
+
+	// control frames carry at most 125 bytes
+	maxControlPayload = 125
+
+	// clients only talk back with control frames, anything larger is dropped
+	maxClientPayload = 64 * 1024
+
+	websocketWriteTimeout = 10 * time.Second
+)
+
+var errWebSocketClosed = errors.New("WebSocket closed")
+
+// origins besides the server's own browsers may open a WebSocket from
+var websocketOrigins = struct {
+	sync.RWMutex
+	origins []string
+}{}
+
+//SetWebSocketOrigins sets the origins such as https://admin.example.com
+//allowed to open a WebSocket besides the one of the server itself
+func SetWebSocketOrigins(origins []string) {
+	websocketOrigins.Lock()
+	defer websocketOrigins.Unlock()
+
+	websocketOrigins.origins = origins
+}
+
+// originAllowed whether a page from the Origin of r may open a WebSocket.
+// Clients other than browsers send no Origin and are allowed
+func originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	websocketOrigins.RLock()
+	defer websocketOrigins.RUnlock()
+
+	for _, o := range websocketOrigins.origins {
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), u.Scheme+"://"+u.Host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//webSocket a server side connection that only pushes text messages
+type webSocket struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+
+	writeLock sync.Mutex
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func headerHasToken(h http.Header, name string, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+//IsWebSocketUpgrade whether the request asks to switch to a WebSocket
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") && headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+func websocketAccept(key string) string {
+	h := sha1.New()
+	io.WriteString(h, key+websocketGUID)
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// upgradeWebSocket completes the opening handshake and takes over the connection
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*webSocket, error) {
+	if r.Method != "GET" {
+		return nil, NewError(http.StatusMethodNotAllowed, "WebSocket handshake requires GET")
+	}
+
+	if r.Header.Get("Sec-Websocket-Version") != websocketVersion {
+		w.Header().Set("Sec-WebSocket-Version", websocketVersion)
+		return nil, NewError(http.StatusUpgradeRequired, "Unsupported WebSocket version '%s'", r.Header.Get("Sec-Websocket-Version"))
+	}
+
+	key := r.Header.Get("Sec-Websocket-Key")
+	if key == "" {
+		return nil, NewError(http.StatusBadRequest, "Missing Sec-WebSocket-Key")
+	}
+
+	// a page of another site must not act with the credentials of the browser
+	if !originAllowed(r) {
+		return nil, NewError(http.StatusForbidden, "WebSocket not allowed from origin '%s'", r.Header.Get("Origin"))
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("Connection does not support WebSocket")
+	}
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", websocketAccept(key))
+
+	err = rw.Flush()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	ws := &webSocket{
+		conn:   conn,
+		rw:     rw,
+		closed: make(chan struct{}),
+	}
+
+	go ws.readLoop()
+
+	return ws, nil
+}
+
+func (ws *webSocket) writeFrame(opcode byte, payload []byte) error {
+	ws.writeLock.Lock()
+	defer ws.writeLock.Unlock()
+
+	header := []byte{0x80 | opcode}
+
+	n := len(payload)
+	switch {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xffff:
+		header = append(header, 126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header = append(header, 127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+
+	ws.conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+
+	_, err := ws.rw.Write(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = ws.rw.Write(payload)
+	if err != nil {
+		return err
+	}
+
+	return ws.rw.Flush()
+}
+
+// readFrame reads one masked client frame
+func (ws *webSocket) readFrame() (byte, []byte, error) {
+	var h [2]byte
+	_, err := io.ReadFull(ws.rw, h[:])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	opcode := h[0] & 0x0f
+	masked := h[1]&0x80 != 0
+	n := uint64(h[1] & 0x7f)
+
+	switch n {
+	case 126:
+		var b [2]byte
+		_, err = io.ReadFull(ws.rw, b[:])
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		_, err = io.ReadFull(ws.rw, b[:])
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if !masked {
+		return 0, nil, errors.New("Unmasked client frame")
+	}
+
+	var mask [4]byte
+	_, err = io.ReadFull(ws.rw, mask[:])
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if opcode >= opClose && n > maxControlPayload {
+		return 0, nil, errors.New("Control frame too large")
+	}
+
+	if n > maxClientPayload {
+		_, err = io.CopyN(ioutil.Discard, ws.rw, int64(n))
+		return opcode, nil, err
+	}
+
+	payload := make([]byte, n)
+	_, err = io.ReadFull(ws.rw, payload)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return opcode, payload, nil
+}
+
+// readLoop answers pings and close frames until the client goes away
+func (ws *webSocket) readLoop() {
+	defer ws.Close()
+
+	for {
+		opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return
+		}
+
+		switch opcode {
+		case opPing:
+			ws.writeFrame(opPong, payload)
+		case opClose:
+			return
+		}
+	}
+}
+
+func (ws *webSocket) WriteText(b []byte) error {
+	select {
+	case <-ws.closed:
+		return errWebSocketClosed
+	default:
+	}
+
+	return ws.writeFrame(opText, b)
+}
+
+func (ws *webSocket) Ping() error {
+	return ws.writeFrame(opPing, nil)
+}
+
+//Done closed once the connection is gone
+func (ws *webSocket) Done() <-chan struct{} {
+	return ws.closed
+}
+
+//Close sends a normal closure and drops the connection
+func (ws *webSocket) Close() {
+	ws.closeOnce.Do(func() {
+		// 1000 normal closure
+		ws.writeFrame(opClose, []byte{0x03, 0xe8})
+
+		close(ws.closed)
+		ws.conn.Close()
+	})
+}
@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package share
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	SetWebSocketOrigins([]string{"https://admin.example.com/"})
+	defer SetWebSocketOrigins(nil)
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"http://router.example.com:8080", true},
+		{"https://admin.example.com", true},
+		{"http://admin.example.com", false},
+		{"https://evil.example.com", false},
+		{"null", false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "http://router.example.com:8080/api/service/systemd/events", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+
+		if got := originAllowed(r); got != tt.want {
+			t.Errorf("originAllowed(%s) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
@@ -3,12 +3,9 @@
 package coredump
 
 import (
-	"bufio"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
 
 	"github.com/RestGW/api-routerd/cmd/share"
 
@@ -29,15 +26,7 @@ type Config struct {
 	JournalSizeMax  string `json:"JournalSizeMax"`
 }
 
-func (c *Config) writeConfig() error {
-	f, err := os.OpenFile(confPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	w := bufio.NewWriter(f)
-
+func (c *Config) writeConfig(r *http.Request) error {
 	conf := "[Coredump]\n"
 
 	if c.Storage != "" {
@@ -70,10 +59,7 @@ func (c *Config) writeConfig() error {
 		conf += "#JournalSizeMax=" + c.JournalSizeMax + "\n"
 	}
 
-	fmt.Fprintln(w, conf)
-	w.Flush()
-
-	return nil
+	return share.WriteConfigFile(r, confPath, conf+"\n", 0644)
 }
 
 func readConf() (*Config, error) {
@@ -115,7 +101,7 @@ func UpdateConf(rw http.ResponseWriter, r *http.Request) error {
 	err = json.Unmarshal([]byte(body), &c)
 	if err != nil {
 		log.Errorf("Failed to Decode HTTP request to json: %v", err)
-		return err
+		return share.BadRequest(err)
 	}
 
 	conf, err := readConf()
@@ -143,7 +129,7 @@ func UpdateConf(rw http.ResponseWriter, r *http.Request) error {
 		conf.ProcessSizeMax = c.ProcessSizeMax
 	}
 
-	err = conf.writeConfig()
+	err = conf.writeConfig(r)
 	if err != nil {
 		log.Errorf("Failed Write to resolv conf: %s", err)
 		return err
@@ -165,7 +151,7 @@ func DeleteConf(rw http.ResponseWriter, r *http.Request) error {
 	err = json.Unmarshal([]byte(body), &c)
 	if err != nil {
 		log.Errorf("Failed to Decode HTTP request to json: %v", err)
-		return err
+		return share.BadRequest(err)
 	}
 
 	conf, err := readConf()
@@ -193,7 +179,7 @@ func DeleteConf(rw http.ResponseWriter, r *http.Request) error {
 		conf.ProcessSizeMax = ""
 	}
 
-	err = conf.writeConfig()
+	err = conf.writeConfig(r)
 	if err != nil {
 		log.Errorf("Failed Write to coredump conf: %v", err)
 		return err
@@ -5,6 +5,7 @@ package firewalld
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/RestGW/api-routerd/cmd/share"
 
@@ -35,7 +36,7 @@ func (f *Firewall) GetFirewalld(rw http.ResponseWriter) error {
 
 	b := firewalldMethods.Contains(f.Property)
 	if !b {
-		return fmt.Errorf("Failed to call method firewalld: %s not found", f.Property)
+		return share.NotFound(fmt.Errorf("Failed to call method firewalld: %s not found", f.Property))
 	}
 
 	log.Debugf("Get Firewalld passthrough: %s", f.Property)
@@ -117,71 +118,65 @@ func (f *Firewall) GetFirewalld(rw http.ResponseWriter) error {
 	return nil
 }
 
-//AddFirewalld wrap all firewalld add
-func (f *Firewall) AddFirewalld(rw http.ResponseWriter) error {
-	c, err := NewConn()
-	if err != nil {
-		return err
-	}
-	defer c.Close()
+// inverse the add or remove undoing f
+func (f *Firewall) inverse() *Firewall {
+	i := *f
 
-	b := firewalldMethods.Contains(f.Property)
-	if !b {
-		return fmt.Errorf("Failed to call method firewalld: %s not found", f.Property)
+	if strings.HasPrefix(f.Property, "add-") {
+		i.Property = "remove-" + strings.TrimPrefix(f.Property, "add-")
+	} else {
+		i.Property = "add-" + strings.TrimPrefix(f.Property, "remove-")
 	}
 
-	log.Debugf("Set Firewalld passthrough: %s", f.Property)
+	return &i
+}
 
+// change runs an add or remove
+func (f *Firewall) change(c *Conn) (string, error) {
 	switch f.Property {
 	case "add-port":
-		var r string
-
 		if f.Permanent == true {
-			r, err = c.AddPortPermanent(f.Zone, f.Port, f.Protocol)
-		} else {
-			r, err = c.AddPort(f.Zone, f.Port, f.Protocol)
+			return c.AddPortPermanent(f.Zone, f.Port, f.Protocol)
 		}
 
-		if err != nil {
-			return err
+		return c.AddPort(f.Zone, f.Port, f.Protocol)
+	case "remove-port":
+		if f.Permanent == true {
+			return c.RemovePortPermanent(f.Zone, f.Port, f.Protocol)
 		}
 
-		return share.JSONResponse(r, rw)
+		return c.RemovePort(f.Zone, f.Port, f.Protocol)
 	case "add-protocol":
-		var r string
-
 		if f.Permanent == true {
-			r, err = c.AddProtocolPermanent(f.Zone, f.Protocol)
-		} else {
-			r, err = c.AddProtocol(f.Zone, f.Protocol)
+			return c.AddProtocolPermanent(f.Zone, f.Protocol)
 		}
 
-		if err != nil {
-			return err
+		return c.AddProtocol(f.Zone, f.Protocol)
+	case "remove-protocol":
+		if f.Permanent == true {
+			return c.RemoveProtocolPermanent(f.Zone, f.Protocol)
 		}
 
-		return share.JSONResponse(r, rw)
+		return c.RemoveProtocol(f.Zone, f.Protocol)
 	case "add-interface":
-		var r string
-
 		if f.Permanent == true {
-			r, err = c.AddInterfacePermanent(f.Zone, f.Interface)
-		} else {
-			r, err = c.AddInterface(f.Zone, f.Interface)
+			return c.AddInterfacePermanent(f.Zone, f.Interface)
 		}
 
-		if err != nil {
-			return err
+		return c.AddInterface(f.Zone, f.Interface)
+	case "remove-interface":
+		if f.Permanent == true {
+			return c.RemoveInterfacePermanent(f.Zone, f.Interface)
 		}
 
-		return share.JSONResponse(r, rw)
+		return c.RemoveInterface(f.Zone, f.Interface)
 	}
 
-	return nil
+	return "", share.NotFound(fmt.Errorf("Failed to call method firewalld: %s not found", f.Property))
 }
 
-//DeleteFirewalld wrap all delete commands
-func (f *Firewall) DeleteFirewalld(rw http.ResponseWriter) error {
+// apply runs an add or remove and registers its inverse
+func (f *Firewall) apply(rw http.ResponseWriter, r *http.Request) error {
 	c, err := NewConn()
 	if err != nil {
 		return err
@@ -190,57 +185,49 @@ func (f *Firewall) DeleteFirewalld(rw http.ResponseWriter) error {
 
 	b := firewalldMethods.Contains(f.Property)
 	if !b {
-		return fmt.Errorf("Failed to call method firewalld: %s not found", f.Property)
+		return share.NotFound(fmt.Errorf("Failed to call method firewalld: %s not found", f.Property))
 	}
 
-	log.Debugf("Delete Firewalld passthrough: %s", f.Property)
-
-	switch f.Property {
-	case "remove-port":
-		var r string
-
-		if f.Permanent == true {
-			r, err = c.RemovePortPermanent(f.Zone, f.Port, f.Protocol)
-		} else {
-			r, err = c.RemovePort(f.Zone, f.Port, f.Protocol)
-		}
+	reply, err := f.change(c)
+	if err != nil {
+		return err
+	}
 
+	undo := f.inverse()
+	share.OnRollback(r, "firewalld "+undo.Property+" in zone "+f.Zone, func() error {
+		c, err := NewConn()
 		if err != nil {
 			return err
 		}
+		defer c.Close()
 
-		return share.JSONResponse(r, rw)
-	case "remove-protocol":
-		var r string
-
-		if f.Permanent == true {
-			r, err = c.RemoveProtocolPermanent(f.Zone, f.Protocol)
-		} else {
-			r, err = c.RemoveProtocol(f.Zone, f.Protocol)
-		}
+		_, err = undo.change(c)
+		return err
+	})
 
-		if err != nil {
-			return err
-		}
+	return share.JSONResponse(reply, rw)
+}
 
-		return share.JSONResponse(r, rw)
-	case "remove-interface":
-		var r string
+//AddFirewalld wrap all firewalld add
+func (f *Firewall) AddFirewalld(rw http.ResponseWriter, r *http.Request) error {
+	if !strings.HasPrefix(f.Property, "add-") {
+		return share.NotFound(fmt.Errorf("Failed to call method firewalld: %s not found", f.Property))
+	}
 
-		if f.Permanent == true {
-			r, err = c.RemoveInterfacePermanent(f.Zone, f.Interface)
-		} else {
-			r, err = c.RemoveInterface(f.Zone, f.Interface)
-		}
+	log.Debugf("Set Firewalld passthrough: %s", f.Property)
 
-		if err != nil {
-			return err
-		}
+	return f.apply(rw, r)
+}
 
-		return share.JSONResponse(r, rw)
+//DeleteFirewalld wrap all delete commands
+func (f *Firewall) DeleteFirewalld(rw http.ResponseWriter, r *http.Request) error {
+	if !strings.HasPrefix(f.Property, "remove-") {
+		return share.NotFound(fmt.Errorf("Failed to call method firewalld: %s not found", f.Property))
 	}
 
-	return nil
+	log.Debugf("Delete Firewalld passthrough: %s", f.Property)
+
+	return f.apply(rw, r)
 }
 
 //Init Init the FW module
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/RestGW/api-routerd/cmd/share"
+
 	"github.com/gorilla/mux"
 )
 
@@ -24,9 +26,11 @@ func routerGetFirewalld(rw http.ResponseWriter, r *http.Request) {
 
 		err := firewall.GetFirewalld(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -37,7 +41,7 @@ func routerConfigureFirewalld(rw http.ResponseWriter, r *http.Request) {
 	firewall := new(Firewall)
 	err := json.NewDecoder(r.Body).Decode(&firewall)
 	if err != nil {
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		share.HTTPError(rw, share.BadRequest(err))
 		return
 	}
 	firewall.Property = property
@@ -45,20 +49,22 @@ func routerConfigureFirewalld(rw http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "POST":
 
-		err = firewall.AddFirewalld(rw)
+		err = firewall.AddFirewalld(rw, r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 
 		break
 	case "DELETE":
 
-		err = firewall.DeleteFirewalld(rw)
+		err = firewall.DeleteFirewalld(rw, r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -66,8 +72,12 @@ func routerConfigureFirewalld(rw http.ResponseWriter, r *http.Request) {
 func RegisterRouterFirewalld(router *mux.Router) {
 	f := router.PathPrefix("/firewalld").Subrouter().StrictSlash(false)
 
-	f.HandleFunc("/get/{property}", routerGetFirewalld)
-	f.HandleFunc("/get/{property}/{value}", routerGetFirewalld)
-	f.HandleFunc("/set/{property}", routerConfigureFirewalld)
-	f.HandleFunc("/delete/{property}", routerConfigureFirewalld)
+	share.Describe(f.HandleFunc("/get/{property}", routerGetFirewalld),
+		share.Op("GET", "A firewalld property", nil, map[string]interface{}{}))
+	share.Describe(f.HandleFunc("/get/{property}/{value}", routerGetFirewalld),
+		share.Op("GET", "A firewalld property of a zone or service", nil, map[string]interface{}{}))
+	share.Describe(f.HandleFunc("/set/{property}", routerConfigureFirewalld),
+		share.Op("POST", "Add a port, protocol or interface to a zone", Firewall{}, map[string]interface{}{}))
+	share.Describe(f.HandleFunc("/delete/{property}", routerConfigureFirewalld),
+		share.Op("DELETE", "Remove a port, protocol or interface from a zone", Firewall{}, map[string]interface{}{}))
 }
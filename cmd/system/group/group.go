@@ -7,6 +7,8 @@ import (
 	"os/exec"
 	"os/user"
 
+	"github.com/RestGW/api-routerd/cmd/share"
+
 	log "github.com/sirupsen/logrus"
 )
 
@@ -28,7 +30,7 @@ func (r *Group) GroupAdd() error {
 	}
 
 	if g != nil {
-		return fmt.Errorf("Failed to add group. Group '%s' already exists", r.Name)
+		return share.Conflict(fmt.Errorf("Failed to add group. Group '%s' already exists", r.Name))
 	}
 
 	id, err := user.LookupGroupId(r.Gid)
@@ -40,7 +42,7 @@ func (r *Group) GroupAdd() error {
 	}
 
 	if id != nil {
-		return fmt.Errorf("Failed to add group '%s': Gid '%s' exists", r.Name, r.Gid)
+		return share.Conflict(fmt.Errorf("Failed to add group '%s': Gid '%s' exists", r.Name, r.Gid))
 	}
 
 	path, err := exec.LookPath("groupadd")
@@ -69,7 +71,7 @@ func (r *Group) GroupDel() error {
 	}
 
 	if g == nil {
-		return fmt.Errorf("Failed to delete group '%s'. Group does not exists", r.Name)
+		return share.NotFound(fmt.Errorf("Failed to delete group '%s'. Group does not exists", r.Name))
 	}
 
 	path, err := exec.LookPath("groupdel")
@@ -98,7 +100,7 @@ func (r *Group) GroupModify() error {
 	}
 
 	if g == nil {
-		return fmt.Errorf("Failed to Modify group '%s'. Group does not exists", r.Name)
+		return share.NotFound(fmt.Errorf("Failed to Modify group '%s'. Group does not exists", r.Name))
 	}
 
 	g, err = user.LookupGroup(r.NewName)
@@ -110,7 +112,7 @@ func (r *Group) GroupModify() error {
 	}
 
 	if g != nil {
-		return fmt.Errorf("Failed to Modify group '%s'. New Group '%s' already exists", r.Name, r.NewName)
+		return share.Conflict(fmt.Errorf("Failed to Modify group '%s'. New Group '%s' already exists", r.Name, r.NewName))
 	}
 
 	path, err := exec.LookPath("groupmod")
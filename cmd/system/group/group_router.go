@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/RestGW/api-routerd/cmd/share"
+
 	"github.com/gorilla/mux"
 )
 
@@ -16,16 +18,19 @@ func routerGroupAdd(rw http.ResponseWriter, r *http.Request) {
 		g := new(Group)
 		err := json.NewDecoder(r.Body).Decode(&g)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
 		err = g.GroupAdd()
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
+		return
 	}
 
 	rw.WriteHeader(http.StatusOK)
@@ -38,16 +43,19 @@ func routerGroupModify(rw http.ResponseWriter, r *http.Request) {
 		g := new(Group)
 		err := json.NewDecoder(r.Body).Decode(&g)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
 		err = g.GroupModify()
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
+		return
 	}
 
 	rw.WriteHeader(http.StatusOK)
@@ -60,16 +68,19 @@ func routerGroupDel(rw http.ResponseWriter, r *http.Request) {
 		g := new(Group)
 		err := json.NewDecoder(r.Body).Decode(&g)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
 		err = g.GroupDel()
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
+		return
 	}
 
 	rw.WriteHeader(http.StatusOK)
@@ -79,7 +90,10 @@ func routerGroupDel(rw http.ResponseWriter, r *http.Request) {
 func RegisterRouterGroup(router *mux.Router) {
 	s := router.PathPrefix("/group").Subrouter().StrictSlash(false)
 
-	s.HandleFunc("/add", routerGroupAdd)
-	s.HandleFunc("/delete", routerGroupDel)
-	s.HandleFunc("/modify", routerGroupModify)
+	share.Describe(s.HandleFunc("/add", routerGroupAdd),
+		share.Op("POST", "Add a group", Group{}, nil))
+	share.Describe(s.HandleFunc("/delete", routerGroupDel),
+		share.Op("DELETE", "Delete a group", Group{}, nil))
+	share.Describe(s.HandleFunc("/modify", routerGroupModify),
+		share.Op("PUT", "Modify a group", Group{}, nil))
 }
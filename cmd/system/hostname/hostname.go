@@ -60,7 +60,7 @@ func (hostname *Hostname) SetHostname() error {
 
 	_, k := hostMethodInfo[hostname.Property]
 	if !k {
-		return fmt.Errorf("Failed to set hostname property: %s not found", hostname.Property)
+		return share.BadRequest(fmt.Errorf("Failed to set hostname property: %s not found", hostname.Property))
 	}
 
 	h := conn.Object(dbusInterface, dbusPath)
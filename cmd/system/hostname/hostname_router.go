@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/RestGW/api-routerd/cmd/share"
+
 	"github.com/gorilla/mux"
 )
 
@@ -23,11 +25,13 @@ func routerGetHostname(rw http.ResponseWriter, r *http.Request) {
 
 		err := GetHostname(rw, property)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -38,23 +42,28 @@ func routerSetHostname(rw http.ResponseWriter, r *http.Request) {
 		hostname := new(Hostname)
 		err := json.NewDecoder(r.Body).Decode(&hostname)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
 		err = hostname.SetHostname()
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
 //RegisterRouterHostname registers with mux
 func RegisterRouterHostname(router *mux.Router) {
 	s := router.PathPrefix("/hostname").Subrouter().StrictSlash(false)
-	s.HandleFunc("", routerGetHostname)
-	s.HandleFunc("/get/{property}", routerGetHostname)
-	s.HandleFunc("/set", routerSetHostname)
+	share.Describe(s.HandleFunc("", routerGetHostname),
+		share.Op("GET", "All hostnamed properties", nil, map[string]string{}))
+	share.Describe(s.HandleFunc("/get/{property}", routerGetHostname),
+		share.Op("GET", "A hostnamed property", nil, Hostname{}))
+	share.Describe(s.HandleFunc("/set", routerSetHostname),
+		share.Op("PUT", "Set a hostnamed property", Hostname{}, nil))
 }
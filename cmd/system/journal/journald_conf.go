@@ -3,12 +3,9 @@
 package journal
 
 import (
-	"bufio"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
 
 	"github.com/RestGW/api-routerd/cmd/share"
 
@@ -52,17 +49,10 @@ var journalConfig = map[string]string{
 	"ReadKMsg":             "",
 }
 
-func writeConfig() error {
-	f, err := os.OpenFile(journalConfPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	w := bufio.NewWriter(f)
-
+func writeConfig(r *http.Request) error {
 	conf := "[Journal]\n"
-	for k, v := range journalConfig {
+	for _, k := range share.SortedKeys(journalConfig) {
+		v := journalConfig[k]
 		if v != "" {
 			conf += k + "=" + v
 		} else {
@@ -71,10 +61,7 @@ func writeConfig() error {
 		conf += "\n"
 	}
 
-	fmt.Fprintln(w, conf)
-	w.Flush()
-
-	return nil
+	return share.WriteConfigFile(r, journalConfPath, conf+"\n", 0644)
 }
 
 func readConf() error {
@@ -112,7 +99,7 @@ func UpdateConf(rw http.ResponseWriter, r *http.Request) error {
 	err = json.Unmarshal([]byte(body), &conf)
 	if err != nil {
 		log.Errorf("Failed to Decode HTTP request to json: %v", err)
-		return err
+		return share.BadRequest(err)
 	}
 
 	err = readConf()
@@ -127,7 +114,7 @@ func UpdateConf(rw http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
-	err = writeConfig()
+	err = writeConfig(r)
 	if err != nil {
 		log.Errorf("Failed Write to journal conf: %v", err)
 		return err
@@ -6,6 +6,9 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/RestGW/api-routerd/cmd/proc"
+	"github.com/RestGW/api-routerd/cmd/share"
+
 	"github.com/gorilla/mux"
 )
 
@@ -14,9 +17,11 @@ func routerGetModules(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := LsMod(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -27,16 +32,19 @@ func routerModProbe(rw http.ResponseWriter, r *http.Request) {
 		kmod := new(KMod)
 		err := json.NewDecoder(r.Body).Decode(&kmod)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
 		err = kmod.ModProbe()
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
+		return
 	}
 
 	rw.WriteHeader(http.StatusOK)
@@ -49,16 +57,19 @@ func routerRmMod(rw http.ResponseWriter, r *http.Request) {
 		kmod := new(KMod)
 		err := json.NewDecoder(r.Body).Decode(&kmod)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
 		err = kmod.RmMod()
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
+		return
 	}
 
 	rw.WriteHeader(http.StatusOK)
@@ -68,7 +79,10 @@ func routerRmMod(rw http.ResponseWriter, r *http.Request) {
 func RegisterRouterKMod(router *mux.Router) {
 	s := router.PathPrefix("/kmod").Subrouter().StrictSlash(false)
 
-	s.HandleFunc("/lsmod", routerGetModules)
-	s.HandleFunc("/modprobe", routerModProbe)
-	s.HandleFunc("/rmmod", routerRmMod)
+	share.Describe(s.HandleFunc("/lsmod", routerGetModules),
+		share.Op("GET", "List kernel modules", nil, []proc.Modules{}))
+	share.Describe(s.HandleFunc("/modprobe", routerModProbe),
+		share.Op("POST", "Load a kernel module", KMod{}, nil))
+	share.Describe(s.HandleFunc("/rmmod", routerRmMod),
+		share.Op("DELETE", "Remove a kernel module", KMod{}, nil))
 }
@@ -42,7 +42,7 @@ func (t *Login) LoginMethodGet(rw http.ResponseWriter) error {
 
 	_, k := loginMethod[t.Path]
 	if !k {
-		return fmt.Errorf("Failed to call method login:  %s not found", t.Path)
+		return share.NotFound(fmt.Errorf("Failed to call method login:  %s not found", t.Path))
 	}
 
 	switch loginMethod[t.Path] {
@@ -75,7 +75,7 @@ func (t *Login) LoginMethodPost(rw http.ResponseWriter) error {
 
 	_, k := loginMethod[t.Path]
 	if !k {
-		return fmt.Errorf("Failed to call method login:  %s not found", t.Path)
+		return share.NotFound(fmt.Errorf("Failed to call method login:  %s not found", t.Path))
 	}
 
 	switch loginMethod[t.Path] {
@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/RestGW/api-routerd/cmd/share"
+
 	"github.com/gorilla/mux"
 )
 
@@ -22,10 +24,12 @@ func routerLoginMethodGet(rw http.ResponseWriter, r *http.Request) {
 
 		err := login.LoginMethodGet(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -36,7 +40,7 @@ func routerLoginMethodPost(rw http.ResponseWriter, r *http.Request) {
 		login := new(Login)
 		err := json.NewDecoder(r.Body).Decode(&login)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
@@ -45,18 +49,22 @@ func routerLoginMethodPost(rw http.ResponseWriter, r *http.Request) {
 
 		err = login.LoginMethodPost(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 
 		rw.WriteHeader(http.StatusOK)
 
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
 //RegisterRouterLogin register with mux
 func RegisterRouterLogin(router *mux.Router) {
 	s := router.PathPrefix("/login").Subrouter().StrictSlash(false)
-	s.HandleFunc("/get/{path}", routerLoginMethodGet)
-	s.HandleFunc("/post/{path}", routerLoginMethodPost)
+	share.Describe(s.HandleFunc("/get/{path}", routerLoginMethodGet),
+		share.Op("GET", "List logind users or sessions", nil, []map[string]interface{}{}))
+	share.Describe(s.HandleFunc("/post/{path}", routerLoginMethodPost),
+		share.Op("POST", "Lock or terminate logind sessions and users", Login{}, nil))
 }
@@ -3,12 +3,9 @@
 package resolv
 
 import (
-	"bufio"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/RestGW/api-routerd/cmd/share"
@@ -26,27 +23,17 @@ type DNSConfig struct {
 	Search  []string `json:"search"`
 }
 
-func (conf *DNSConfig) writeConfig() error {
-	f, err := os.OpenFile(resolvConfPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	w := bufio.NewWriter(f)
+func (conf *DNSConfig) writeConfig(r *http.Request) error {
+	var lines []string
 
 	for _, server := range conf.Servers {
-		line := "nameserver " + server
-		fmt.Fprintln(w, line)
+		lines = append(lines, "nameserver "+server)
 	}
 	for _, s := range conf.Search {
-		line := "search " + s
-		fmt.Fprintln(w, line)
+		lines = append(lines, "search "+s)
 	}
 
-	w.Flush()
-
-	return nil
+	return share.WriteConfigFile(r, resolvConfPath, share.RenderLines(lines), 0644)
 }
 
 func readConf() (*DNSConfig, error) {
@@ -115,7 +102,7 @@ func UpdateConf(rw http.ResponseWriter, r *http.Request) error {
 	err = json.Unmarshal([]byte(body), &dns)
 	if err != nil {
 		log.Error("Failed to Decode HTTP request to json: ", err)
-		return err
+		return share.BadRequest(err)
 	}
 
 	conf, err := readConf()
@@ -140,7 +127,7 @@ func UpdateConf(rw http.ResponseWriter, r *http.Request) error {
 		conf.Search = append(conf.Search, s)
 	}
 
-	err = conf.writeConfig()
+	err = conf.writeConfig(r)
 	if err != nil {
 		log.Errorf("Failed Write to resolv conf: %s", err)
 		return err
@@ -165,7 +152,7 @@ func DeleteConf(rw http.ResponseWriter, r *http.Request) error {
 	err = json.Unmarshal([]byte(body), &dns)
 	if err != nil {
 		log.Error("Failed to Decode HTTP request to json: ", err)
-		return err
+		return share.BadRequest(err)
 	}
 
 	conf, err := readConf()
@@ -191,7 +178,7 @@ func DeleteConf(rw http.ResponseWriter, r *http.Request) error {
 		conf.Search, _ = share.StringDeleteSlice(conf.Search, s)
 	}
 
-	err = conf.writeConfig()
+	err = conf.writeConfig(r)
 	if err != nil {
 		log.Errorf("Failed Write to resolv conf: %s", err)
 		return err
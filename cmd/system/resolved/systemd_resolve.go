@@ -3,12 +3,9 @@
 package resolved
 
 import (
-	"bufio"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/RestGW/api-routerd/cmd/share"
@@ -27,15 +24,7 @@ type DNSConfig struct {
 	FallbackDNS []string `json:"fallback_dns"`
 }
 
-func (d *DNSConfig) writeConfig() error {
-	f, err := os.OpenFile(resolvedConfPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	w := bufio.NewWriter(f)
-
+func (d *DNSConfig) writeConfig(r *http.Request) error {
 	conf := "[Resolve]\n"
 
 	dnsConf := "DNS="
@@ -50,10 +39,7 @@ func (d *DNSConfig) writeConfig() error {
 	}
 	conf += fallbackDNS + "\n"
 
-	fmt.Fprintln(w, conf)
-	w.Flush()
-
-	return nil
+	return share.WriteConfigFile(r, resolvedConfPath, conf+"\n", 0644)
 }
 
 func readConf() (*DNSConfig, error) {
@@ -99,7 +85,7 @@ func UpdateConf(rw http.ResponseWriter, r *http.Request) error {
 	err = json.Unmarshal([]byte(body), &dns)
 	if err != nil {
 		log.Errorf("Failed to Decode HTTP request to json: %v", err)
-		return err
+		return share.BadRequest(err)
 	}
 
 	conf, err := readConf()
@@ -124,7 +110,7 @@ func UpdateConf(rw http.ResponseWriter, r *http.Request) error {
 		conf.FallbackDNS = append(conf.FallbackDNS, s)
 	}
 
-	err = conf.writeConfig()
+	err = conf.writeConfig(r)
 	if err != nil {
 		log.Errorf("Failed Write to resolv conf: %v", err)
 		return err
@@ -149,7 +135,7 @@ func DeleteConf(rw http.ResponseWriter, r *http.Request) error {
 	err = json.Unmarshal([]byte(body), &dns)
 	if err != nil {
 		log.Errorf("Failed to Decode HTTP request to json: %v", err)
-		return err
+		return share.BadRequest(err)
 	}
 
 	conf, err := readConf()
@@ -175,7 +161,7 @@ func DeleteConf(rw http.ResponseWriter, r *http.Request) error {
 		conf.FallbackDNS, _ = share.StringDeleteSlice(conf.FallbackDNS, s)
 	}
 
-	err = conf.writeConfig()
+	err = conf.writeConfig(r)
 	if err != nil {
 		log.Errorf("Failed Write to resolv conf: %v", err)
 		return err
@@ -24,30 +24,31 @@ type Sysctl struct {
 	Apply string `json:"apply"`
 }
 
-// Apply sysctl conf to system
-func (s *Sysctl) apply() error {
+// applyRequested whether the file is loaded after the change
+func (s *Sysctl) applyRequested() (bool, error) {
 	b, err := share.ParseBool(s.Apply)
 	if err != nil {
-		return fmt.Errorf("Failed to apply: %s", s.Key)
+		return false, share.BadRequest(fmt.Errorf("Failed to apply: %s", s.Key))
 	}
 
-	if b != true {
-		return nil
-	}
+	return b, nil
+}
 
+//Apply load sysctl conf to system. Runs as a job
+func Apply() (string, error) {
 	path, err := exec.LookPath("sysctl")
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	cmd := exec.Command(path, "-p")
 	stdout, err := cmd.CombinedOutput()
 	if err != nil {
 		log.Errorf("Failed to load sysctl variable: %s", stdout)
-		return fmt.Errorf("Failed to load sysctl variable: %s", stdout)
+		return "", fmt.Errorf("Failed to load sysctl variable: %s", stdout)
 	}
 
-	return nil
+	return "done", nil
 }
 
 // Read sysctl config to a map
@@ -73,17 +74,14 @@ func readConfig() (map[string]string, error) {
 	return sysctl, nil
 }
 
-//WriteConfig write config to file
-func writeConfig(sysctl map[string]string) error {
+//WriteConfig write config to file sorted by key so diffs stay small
+func writeConfig(r *http.Request, sysctl map[string]string) error {
 	var lines []string
-	var line string
-
-	for k, v := range sysctl {
-		line = k + "=" + v
-		lines = append(lines, line)
+	for _, k := range share.SortedKeys(sysctl) {
+		lines = append(lines, k+"="+sysctl[k])
 	}
 
-	return share.WriteFullFile(sysctlPath, lines)
+	return share.WriteConfigFile(r, sysctlPath, share.RenderLines(lines), 0644)
 }
 
 // Get read sysctl file
@@ -97,7 +95,7 @@ func Get(rw http.ResponseWriter) error {
 }
 
 // Update update sysctl file
-func (s *Sysctl) Update() error {
+func (s *Sysctl) Update(r *http.Request) error {
 	sysctl, err := readConfig()
 	if err != nil {
 		return err
@@ -105,16 +103,11 @@ func (s *Sysctl) Update() error {
 
 	sysctl[s.Key] = s.Value
 
-	err = writeConfig(sysctl)
-	if err != nil {
-		return err
-	}
-
-	return s.apply()
+	return writeConfig(r, sysctl)
 }
 
 // Delete delete sysctl value in file
-func (s *Sysctl) Delete() error {
+func (s *Sysctl) Delete(r *http.Request) error {
 	sysctl, err := readConfig()
 	if err != nil {
 		return err
@@ -122,14 +115,10 @@ func (s *Sysctl) Delete() error {
 
 	_, ok := sysctl[s.Key]
 	if !ok {
-		return fmt.Errorf("Failed to delete sysctl parameter '%s'. Key not found", s.Key)
+		return share.NotFound(fmt.Errorf("Failed to delete sysctl parameter '%s'. Key not found", s.Key))
 	}
 
 	delete(sysctl, s.Key)
-	err = writeConfig(sysctl)
-	if err != nil {
-		return err
-	}
 
-	return s.apply()
+	return writeConfig(r, sysctl)
 }
@@ -6,6 +6,9 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/RestGW/api-routerd/cmd/jobs"
+	"github.com/RestGW/api-routerd/cmd/share"
+
 	"github.com/gorilla/mux"
 )
 
@@ -14,9 +17,11 @@ func routerSysctlGet(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := Get(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -26,16 +31,34 @@ func routerSysctlUpdate(rw http.ResponseWriter, r *http.Request) {
 		s := new(Sysctl)
 		err := json.NewDecoder(r.Body).Decode(&s)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
+			return
+		}
+
+		apply, err := s.applyRequested()
+		if err != nil {
+			share.HTTPError(rw, err)
 			return
 		}
 
-		err = s.Update()
+		err = s.Update(r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
+			return
+		}
+
+		// a dry run only previews the file
+		if apply && !share.IsDryRun(r) {
+			err = jobs.Run(rw, r, "sysctl -p", Apply)
+			if err != nil {
+				share.HTTPError(rw, err)
+			}
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
+		return
 	}
 
 	rw.WriteHeader(http.StatusOK)
@@ -47,16 +70,34 @@ func routerSysctlDelete(rw http.ResponseWriter, r *http.Request) {
 		s := new(Sysctl)
 		err := json.NewDecoder(r.Body).Decode(&s)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
-		err = s.Delete()
+		apply, err := s.applyRequested()
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
+			return
+		}
+
+		err = s.Delete(r)
+		if err != nil {
+			share.HTTPError(rw, err)
+			return
+		}
+
+		// a dry run only previews the file
+		if apply && !share.IsDryRun(r) {
+			err = jobs.Run(rw, r, "sysctl -p", Apply)
+			if err != nil {
+				share.HTTPError(rw, err)
+			}
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
+		return
 	}
 
 	rw.WriteHeader(http.StatusOK)
@@ -66,8 +107,14 @@ func routerSysctlDelete(rw http.ResponseWriter, r *http.Request) {
 func RegisterRouterSysctl(router *mux.Router) {
 	s := router.PathPrefix("/sysctl").Subrouter().StrictSlash(false)
 
-	s.HandleFunc("/get", routerSysctlGet)
-	s.HandleFunc("/add", routerSysctlUpdate)
-	s.HandleFunc("/modify", routerSysctlUpdate)
-	s.HandleFunc("/delete", routerSysctlDelete)
+	share.Describe(s.HandleFunc("/get", routerSysctlGet),
+		share.Op("GET", "Read sysctl.conf", nil, map[string]string{}))
+	share.Describe(s.HandleFunc("/add", routerSysctlUpdate),
+		share.Op("POST", "Add a key to sysctl.conf, with apply replies 202 with a job", Sysctl{}, jobs.Job{}).Previewable(),
+		share.Op("PUT", "Add a key to sysctl.conf, with apply replies 202 with a job", Sysctl{}, jobs.Job{}).Previewable())
+	share.Describe(s.HandleFunc("/modify", routerSysctlUpdate),
+		share.Op("POST", "Modify a key of sysctl.conf, with apply replies 202 with a job", Sysctl{}, jobs.Job{}).Previewable(),
+		share.Op("PUT", "Modify a key of sysctl.conf, with apply replies 202 with a job", Sysctl{}, jobs.Job{}).Previewable())
+	share.Describe(s.HandleFunc("/delete", routerSysctlDelete),
+		share.Op("DELETE", "Delete a key from sysctl.conf, with apply replies 202 with a job", Sysctl{}, jobs.Job{}).Previewable())
 }
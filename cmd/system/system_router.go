@@ -5,6 +5,8 @@ package system
 import (
 	"net/http"
 
+	apiconf "github.com/RestGW/api-routerd/cmd/conf"
+	"github.com/RestGW/api-routerd/cmd/share"
 	"github.com/RestGW/api-routerd/cmd/system/conf"
 	"github.com/RestGW/api-routerd/cmd/system/coredump"
 	"github.com/RestGW/api-routerd/cmd/system/firewalld"
@@ -29,16 +31,18 @@ func routerConfigureJournalConf(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := journal.GetConf(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
 
 	case "POST":
 		err := journal.UpdateConf(rw, r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -48,7 +52,7 @@ func configureResolv(rw http.ResponseWriter, r *http.Request) {
 
 		err := resolv.GetConf(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
@@ -56,7 +60,7 @@ func configureResolv(rw http.ResponseWriter, r *http.Request) {
 
 		err := resolv.UpdateConf(rw, r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
@@ -64,10 +68,12 @@ func configureResolv(rw http.ResponseWriter, r *http.Request) {
 
 		err := resolv.DeleteConf(rw, r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -77,7 +83,7 @@ func configureSystemdResolved(rw http.ResponseWriter, r *http.Request) {
 
 		err := resolved.GetConf(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
@@ -85,7 +91,7 @@ func configureSystemdResolved(rw http.ResponseWriter, r *http.Request) {
 
 		err := resolved.UpdateConf(rw, r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
@@ -93,10 +99,12 @@ func configureSystemdResolved(rw http.ResponseWriter, r *http.Request) {
 
 		err := resolved.DeleteConf(rw, r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -106,7 +114,7 @@ func configureSystemdTimeSyncd(rw http.ResponseWriter, r *http.Request) {
 
 		err := timesyncd.GetConf(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
@@ -114,7 +122,7 @@ func configureSystemdTimeSyncd(rw http.ResponseWriter, r *http.Request) {
 
 		err := timesyncd.UpdateConf(rw, r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
@@ -122,10 +130,12 @@ func configureSystemdTimeSyncd(rw http.ResponseWriter, r *http.Request) {
 
 		err := timesyncd.DeleteConf(rw, r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -135,7 +145,7 @@ func configureSystemdCoreDump(rw http.ResponseWriter, r *http.Request) {
 
 		err := coredump.GetConf(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
@@ -143,7 +153,7 @@ func configureSystemdCoreDump(rw http.ResponseWriter, r *http.Request) {
 
 		err := coredump.UpdateConf(rw, r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
@@ -151,17 +161,19 @@ func configureSystemdCoreDump(rw http.ResponseWriter, r *http.Request) {
 
 		err := coredump.DeleteConf(rw, r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
 func readSudoersConfig(rw http.ResponseWriter, req *http.Request) {
 	err := conf.GetSudoers(rw)
 	if err != nil {
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		share.HTTPError(rw, err)
 		return
 	}
 }
@@ -169,7 +181,7 @@ func readSudoersConfig(rw http.ResponseWriter, req *http.Request) {
 func readSSHConfig(rw http.ResponseWriter, req *http.Request) {
 	err := conf.SSHConfFileRead(rw)
 	if err != nil {
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		share.HTTPError(rw, err)
 		return
 	}
 }
@@ -179,64 +191,105 @@ func RegisterRouterSystem(router *mux.Router) {
 	n := router.PathPrefix("/system").Subrouter()
 
 	// hostname
-	hostname.RegisterRouterHostname(n)
+	if apiconf.ModuleEnabled("hostname") {
+		hostname.RegisterRouterHostname(n)
+	}
 
 	// timedate
-	timedate.RegisterRouterTimeDate(n)
+	if apiconf.ModuleEnabled("timedate") {
+		timedate.RegisterRouterTimeDate(n)
+	}
 
 	// kmod
-	kmod.RegisterRouterKMod(n)
+	if apiconf.ModuleEnabled("kmod") {
+		kmod.RegisterRouterKMod(n)
+	}
 
 	// group
-	group.RegisterRouterGroup(n)
+	if apiconf.ModuleEnabled("group") {
+		group.RegisterRouterGroup(n)
+	}
 
 	// user
-	user.RegisterRouterUser(n)
+	if apiconf.ModuleEnabled("user") {
+		user.RegisterRouterUser(n)
+	}
 
 	// sysctl
-	sysctl.RegisterRouterSysctl(n)
+	if apiconf.ModuleEnabled("sysctl") {
+		sysctl.RegisterRouterSysctl(n)
+	}
 
 	// login
-	login.RegisterRouterLogin(n)
+	if apiconf.ModuleEnabled("login") {
+		login.RegisterRouterLogin(n)
+	}
 
 	// firewalld
-	err := firewalld.Init()
-	if err != nil {
-		log.Errorf("Failed to init firewalld: %s", err)
-		return
+	if apiconf.ModuleEnabled("firewalld") {
+		err := firewalld.Init()
+		if err != nil {
+			log.Errorf("Failed to init firewalld: %s", err)
+		} else {
+			firewalld.RegisterRouterFirewalld(n)
+		}
 	}
 
-	firewalld.RegisterRouterFirewalld(n)
-
 	// conf
-	n.HandleFunc("/journal/conf", routerConfigureJournalConf)
-	n.HandleFunc("/journal/conf/update", routerConfigureJournalConf)
+	journalOps := []share.Operation{
+		share.Op("GET", "Read journald.conf", nil, map[string]string{}),
+		share.Op("POST", "Update journald.conf", map[string]string{}, map[string]string{}).Previewable(),
+	}
+	share.Describe(n.HandleFunc("/journal/conf", routerConfigureJournalConf), journalOps...)
+	share.Describe(n.HandleFunc("/journal/conf/update", routerConfigureJournalConf), journalOps...)
 
 	// resolv.conf
-	n.HandleFunc("/resolv", configureResolv)
-	n.HandleFunc("/resolv/get", configureResolv)
-	n.HandleFunc("/resolv/add", configureResolv)
-	n.HandleFunc("/resolv/delete", configureResolv)
+	resolvOps := []share.Operation{
+		share.Op("GET", "Read resolv.conf", nil, resolv.DNSConfig{}),
+		share.Op("POST", "Add nameservers and search domains to resolv.conf", resolv.DNSConfig{}, resolv.DNSConfig{}).Previewable(),
+		share.Op("DELETE", "Remove nameservers and search domains from resolv.conf", resolv.DNSConfig{}, resolv.DNSConfig{}).Previewable(),
+	}
+	share.Describe(n.HandleFunc("/resolv", configureResolv), resolvOps...)
+	share.Describe(n.HandleFunc("/resolv/get", configureResolv), resolvOps...)
+	share.Describe(n.HandleFunc("/resolv/add", configureResolv), resolvOps...)
+	share.Describe(n.HandleFunc("/resolv/delete", configureResolv), resolvOps...)
 
 	// systemd-resolved
-	n.HandleFunc("/systemdresolved", configureSystemdResolved)
-	n.HandleFunc("/systemdresolved/get", configureSystemdResolved)
-	n.HandleFunc("/systemdresolved/add", configureSystemdResolved)
-	n.HandleFunc("/systemdresolved/delete", configureSystemdResolved)
+	resolvedOps := []share.Operation{
+		share.Op("GET", "Read resolved.conf", nil, resolved.DNSConfig{}),
+		share.Op("POST", "Add DNS servers to resolved.conf", resolved.DNSConfig{}, resolved.DNSConfig{}).Previewable(),
+		share.Op("DELETE", "Remove DNS servers from resolved.conf", resolved.DNSConfig{}, resolved.DNSConfig{}).Previewable(),
+	}
+	share.Describe(n.HandleFunc("/systemdresolved", configureSystemdResolved), resolvedOps...)
+	share.Describe(n.HandleFunc("/systemdresolved/get", configureSystemdResolved), resolvedOps...)
+	share.Describe(n.HandleFunc("/systemdresolved/add", configureSystemdResolved), resolvedOps...)
+	share.Describe(n.HandleFunc("/systemdresolved/delete", configureSystemdResolved), resolvedOps...)
 
 	// systemd-timesyncd
-	n.HandleFunc("/systemdtimesyncd", configureSystemdTimeSyncd)
-	n.HandleFunc("/systemdtimesyncd/get", configureSystemdTimeSyncd)
-	n.HandleFunc("/systemdtimesyncd/add", configureSystemdTimeSyncd)
-	n.HandleFunc("/systemdtimesyncd/delete", configureSystemdTimeSyncd)
+	timesyncdOps := []share.Operation{
+		share.Op("GET", "Read timesyncd.conf", nil, timesyncd.TimeSyncConfig{}),
+		share.Op("POST", "Add NTP servers to timesyncd.conf", timesyncd.TimeSyncConfig{}, timesyncd.TimeSyncConfig{}).Previewable(),
+		share.Op("DELETE", "Remove NTP servers from timesyncd.conf", timesyncd.TimeSyncConfig{}, timesyncd.TimeSyncConfig{}).Previewable(),
+	}
+	share.Describe(n.HandleFunc("/systemdtimesyncd", configureSystemdTimeSyncd), timesyncdOps...)
+	share.Describe(n.HandleFunc("/systemdtimesyncd/get", configureSystemdTimeSyncd), timesyncdOps...)
+	share.Describe(n.HandleFunc("/systemdtimesyncd/add", configureSystemdTimeSyncd), timesyncdOps...)
+	share.Describe(n.HandleFunc("/systemdtimesyncd/delete", configureSystemdTimeSyncd), timesyncdOps...)
 
 	// coredump.conf
-	n.HandleFunc("/coredump", configureSystemdCoreDump)
-	n.HandleFunc("/coredump/get", configureSystemdCoreDump)
-	n.HandleFunc("/coredump/add", configureSystemdCoreDump)
-	n.HandleFunc("/coredump/delete", configureSystemdCoreDump)
+	coredumpOps := []share.Operation{
+		share.Op("GET", "Read coredump.conf", nil, coredump.Config{}),
+		share.Op("POST", "Update coredump.conf", coredump.Config{}, coredump.Config{}).Previewable(),
+		share.Op("DELETE", "Reset keys of coredump.conf", coredump.Config{}, coredump.Config{}).Previewable(),
+	}
+	share.Describe(n.HandleFunc("/coredump", configureSystemdCoreDump), coredumpOps...)
+	share.Describe(n.HandleFunc("/coredump/get", configureSystemdCoreDump), coredumpOps...)
+	share.Describe(n.HandleFunc("/coredump/add", configureSystemdCoreDump), coredumpOps...)
+	share.Describe(n.HandleFunc("/coredump/delete", configureSystemdCoreDump), coredumpOps...)
 
 	// Generic system confs
-	n.HandleFunc("/conf/sudoers", readSudoersConfig)
-	n.HandleFunc("/conf/sshd", readSSHConfig)
+	share.Describe(n.HandleFunc("/conf/sudoers", readSudoersConfig),
+		share.Op("GET", "Read sudoers", nil, conf.SudoersConf{}))
+	share.Describe(n.HandleFunc("/conf/sshd", readSSHConfig),
+		share.Op("GET", "Read sshd_config", nil, map[string]string{}))
 }
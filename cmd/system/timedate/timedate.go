@@ -53,7 +53,7 @@ func (t *TimeDate) SetTimeDate() error {
 
 	_, k := timeDateMethod[t.Property]
 	if !k {
-		return fmt.Errorf("Failed to set timedate:  %s not found", t.Property)
+		return share.BadRequest(fmt.Errorf("Failed to set timedate:  %s not found", t.Property))
 	}
 
 	h := conn.Object(dbusInterface, dbusPath)
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/RestGW/api-routerd/cmd/share"
+
 	"github.com/gorilla/mux"
 )
 
@@ -24,10 +26,12 @@ func routerGetTimeDate(rw http.ResponseWriter, r *http.Request) {
 
 		err := GetTimeDate(rw, property)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -38,23 +42,28 @@ func routerSetTimeDate(rw http.ResponseWriter, r *http.Request) {
 		timedate := new(TimeDate)
 		err := json.NewDecoder(r.Body).Decode(&timedate)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
 		fmt.Println(timedate)
 		err = timedate.SetTimeDate()
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
 //RegisterRouterTimeDate register with mux
 func RegisterRouterTimeDate(router *mux.Router) {
 	s := router.PathPrefix("/timedate").Subrouter().StrictSlash(false)
-	s.HandleFunc("", routerGetTimeDate)
-	s.HandleFunc("/get/{property}", routerGetTimeDate)
-	s.HandleFunc("/set", routerSetTimeDate)
+	share.Describe(s.HandleFunc("", routerGetTimeDate),
+		share.Op("GET", "All timedated properties", nil, map[string]string{}))
+	share.Describe(s.HandleFunc("/get/{property}", routerGetTimeDate),
+		share.Op("GET", "A timedated property", nil, TimeDate{}))
+	share.Describe(s.HandleFunc("/set", routerSetTimeDate),
+		share.Op("PUT", "Set a timedated property", TimeDate{}, nil))
 }
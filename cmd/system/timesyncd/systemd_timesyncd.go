@@ -1,12 +1,9 @@
 package timesyncd
 
 import (
-	"bufio"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/RestGW/api-routerd/cmd/share"
@@ -28,15 +25,7 @@ type TimeSyncConfig struct {
 	PollIntervalMaxSec string   `json:"PollIntervalMaxSec"`
 }
 
-func (t *TimeSyncConfig) writeConf() error {
-	f, err := os.OpenFile(timeSyncdConfPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	w := bufio.NewWriter(f)
-
+func (t *TimeSyncConfig) writeConf(r *http.Request) error {
 	conf := "[Time]\n"
 
 	ntpConf := "NTP="
@@ -63,10 +52,7 @@ func (t *TimeSyncConfig) writeConf() error {
 		conf += "PollIntervalMaxSec=" + t.PollIntervalMaxSec + "\n"
 	}
 
-	fmt.Fprintln(w, conf)
-	w.Flush()
-
-	return nil
+	return share.WriteConfigFile(r, timeSyncdConfPath, conf+"\n", 0644)
 }
 
 func readConf() (*TimeSyncConfig, error) {
@@ -112,7 +98,7 @@ func UpdateConf(rw http.ResponseWriter, r *http.Request) error {
 	err = json.Unmarshal([]byte(body), &t)
 	if err != nil {
 		log.Errorf("Failed to Decode HTTP request to json: %v", err)
-		return err
+		return share.BadRequest(err)
 	}
 
 	conf, err := readConf()
@@ -149,7 +135,7 @@ func UpdateConf(rw http.ResponseWriter, r *http.Request) error {
 		conf.PollIntervalMaxSec = "t.PollIntervalMaxSec"
 	}
 
-	err = conf.writeConf()
+	err = conf.writeConf(r)
 	if err != nil {
 		log.Errorf("Failed Write to time sync conf: %v", err)
 		return err
@@ -174,7 +160,7 @@ func DeleteConf(rw http.ResponseWriter, r *http.Request) error {
 	err = json.Unmarshal([]byte(body), &t)
 	if err != nil {
 		log.Errorf("Failed to Decode HTTP request to json: %v", err)
-		return err
+		return share.BadRequest(err)
 	}
 
 	conf, err := readConf()
@@ -212,7 +198,7 @@ func DeleteConf(rw http.ResponseWriter, r *http.Request) error {
 		conf.PollIntervalMaxSec = "t.PollIntervalMaxSec"
 	}
 
-	err = conf.writeConf()
+	err = conf.writeConf(r)
 	if err != nil {
 		log.Errorf("Failed Write to time sync conf: %v", err)
 		return err
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"os/user"
+	"sync"
 
 	"github.com/RestGW/api-routerd/cmd/share"
 
@@ -29,8 +30,11 @@ type User struct {
 	Password      string   `json:"password"`
 }
 
-//Add add user
-func (r *User) Add() error {
+// newusers reads its input from one file
+var userFileLock sync.Mutex
+
+//Check the user and UID are free before Add
+func (r *User) Check() error {
 	u, err := user.Lookup(r.Username)
 	if err != nil {
 		_, ok := err.(user.UnknownUserError)
@@ -40,7 +44,7 @@ func (r *User) Add() error {
 	}
 
 	if u != nil {
-		return fmt.Errorf("Failed to add user '%s' already exists", r.Username)
+		return share.Conflict(fmt.Errorf("Failed to add user '%s' already exists", r.Username))
 	}
 
 	if r.UID != "" {
@@ -53,31 +57,40 @@ func (r *User) Add() error {
 		}
 
 		if id != nil {
-			return fmt.Errorf("Failed to add user '%s': Gid '%s' exists", r.Username, r.Gid)
+			return share.Conflict(fmt.Errorf("Failed to add user '%s': Gid '%s' exists", r.Username, r.Gid))
 		}
 	}
 
+	return nil
+}
+
+//Add add user with newusers. Runs as a job
+func (r *User) Add() (string, error) {
+	userFileLock.Lock()
+	defer userFileLock.Unlock()
+
 	//<Username>:<Password>:<UID>:<GID>:<User Info>:<Home Dir>:<Default Shell>
 	line := r.Username + ":" + r.Password + ":" + r.UID + ":" + r.Gid + ":" + r.Comment + ":" + r.HomeDirectory + ":" + r.Shell
 
-	err = share.WriteOneLineFile(userFile, line)
+	err := share.WriteOneLineFile(userFile, line)
 	if err != nil {
-		return err
+		return "", err
 	}
+	defer os.Remove(userFile)
 
 	path, err := exec.LookPath("newusers")
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	cmd := exec.Command(path, userFile)
 	stdout, err := cmd.CombinedOutput()
 	if err != nil {
 		log.Errorf("Failed to add user %s: %s", r.Username, stdout)
-		return fmt.Errorf("Failed to add user '%s': %s", r.Username, stdout)
+		return "", fmt.Errorf("Failed to add user '%s': %s", r.Username, stdout)
 	}
 
-	return os.Remove(userFile)
+	return "done", nil
 }
 
 //Del delete user
@@ -91,7 +104,7 @@ func (r *User) Del() error {
 	}
 
 	if g == nil {
-		return fmt.Errorf("Failed to delete user '%s'. User does not exists", r.Username)
+		return share.NotFound(fmt.Errorf("Failed to delete user '%s'. User does not exists", r.Username))
 	}
 
 	path, err := exec.LookPath("userdel")
@@ -120,7 +133,7 @@ func (r *User) Modify() error {
 	}
 
 	if g == nil {
-		return fmt.Errorf("Failed to Modify user '%s'. User does not exists", r.Username)
+		return share.NotFound(fmt.Errorf("Failed to Modify user '%s'. User does not exists", r.Username))
 	}
 
 	path, err := exec.LookPath("usermod")
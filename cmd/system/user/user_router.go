@@ -6,6 +6,9 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/RestGW/api-routerd/cmd/jobs"
+	"github.com/RestGW/api-routerd/cmd/share"
+
 	"github.com/gorilla/mux"
 )
 
@@ -16,19 +19,24 @@ func routerAdd(rw http.ResponseWriter, r *http.Request) {
 		u := new(User)
 		err := json.NewDecoder(r.Body).Decode(&u)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
-		err = u.Add()
+		err = u.Check()
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
+
+		err = jobs.Run(rw, r, "add user "+u.Username, u.Add)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
-
-	rw.WriteHeader(http.StatusOK)
 }
 
 func routerModify(rw http.ResponseWriter, r *http.Request) {
@@ -38,16 +46,19 @@ func routerModify(rw http.ResponseWriter, r *http.Request) {
 		u := new(User)
 		err := json.NewDecoder(r.Body).Decode(&u)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
 		err = u.Modify()
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
+		return
 	}
 
 	rw.WriteHeader(http.StatusOK)
@@ -60,16 +71,19 @@ func routerDel(rw http.ResponseWriter, r *http.Request) {
 		u := new(User)
 		err := json.NewDecoder(r.Body).Decode(&u)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
 		err = u.Del()
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 			return
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
+		return
 	}
 
 	rw.WriteHeader(http.StatusOK)
@@ -79,7 +93,10 @@ func routerDel(rw http.ResponseWriter, r *http.Request) {
 func RegisterRouterUser(router *mux.Router) {
 	s := router.PathPrefix("/user").Subrouter().StrictSlash(false)
 
-	s.HandleFunc("/add", routerAdd)
-	s.HandleFunc("/delete", routerDel)
-	s.HandleFunc("/modify", routerModify)
+	share.Describe(s.HandleFunc("/add", routerAdd),
+		share.Op("POST", "Add a user, replies 202 with a job", User{}, jobs.Job{}))
+	share.Describe(s.HandleFunc("/delete", routerDel),
+		share.Op("DELETE", "Delete a user", User{}, nil))
+	share.Describe(s.HandleFunc("/modify", routerModify),
+		share.Op("PUT", "Modify a user", User{}, nil))
 }
@@ -0,0 +1,362 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package systemd
+
+import (
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	sd "github.com/coreos/go-systemd/dbus"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ListUnits is polled to catch units that appear or go away;
+	// state transitions arrive right away as PropertiesChanged signals
+	eventsPollInterval = 2 * time.Second
+
+	// events a slow client may lag behind before it is dropped
+	eventsBuffer = 256
+)
+
+// unit event types
+const (
+	UnitEventNew     = "new"
+	UnitEventChanged = "changed"
+	UnitEventRemoved = "removed"
+)
+
+//UnitEvent a unit appeared, went away or changed its ActiveState or SubState
+type UnitEvent struct {
+	Type           string    `json:"type"`
+	Unit           string    `json:"unit"`
+	LoadState      string    `json:"load_state,omitempty"`
+	ActiveState    string    `json:"active_state,omitempty"`
+	SubState       string    `json:"sub_state,omitempty"`
+	OldActiveState string    `json:"old_active_state,omitempty"`
+	OldSubState    string    `json:"old_sub_state,omitempty"`
+	Time           time.Time `json:"time"`
+}
+
+type unitState struct {
+	LoadState   string
+	ActiveState string
+	SubState    string
+}
+
+//UnitSubscriber receives the events of the units matching its globs
+type UnitSubscriber struct {
+	Events <-chan *UnitEvent
+
+	events chan *UnitEvent
+	globs  []string
+}
+
+func (s *UnitSubscriber) matches(unit string) bool {
+	if len(s.globs) == 0 {
+		return true
+	}
+
+	for _, g := range s.globs {
+		if ok, _ := path.Match(g, unit); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unitWatcher one D-Bus subscription shared by every client. It runs while
+// there are subscribers
+type unitWatcher struct {
+	lock        sync.Mutex
+	conn        *sd.Conn
+	stop        chan struct{}
+	units       map[string]unitState
+	subscribers map[*UnitSubscriber]struct{}
+}
+
+var watcher = &unitWatcher{
+	subscribers: make(map[*UnitSubscriber]struct{}),
+}
+
+//ValidUnitGlob whether pattern is a valid unit glob
+func ValidUnitGlob(pattern string) bool {
+	_, err := path.Match(pattern, "")
+	return err == nil
+}
+
+//SubscribeUnits streams the state changes of the units matching globs,
+//all units when there are none
+func SubscribeUnits(globs []string) (*UnitSubscriber, error) {
+	w := watcher
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.conn == nil {
+		err := w.start()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ch := make(chan *UnitEvent, eventsBuffer)
+	s := &UnitSubscriber{
+		Events: ch,
+		events: ch,
+		globs:  globs,
+	}
+
+	w.subscribers[s] = struct{}{}
+
+	return s, nil
+}
+
+//UnsubscribeUnits stops s. The watcher goes idle with the last subscriber
+func UnsubscribeUnits(s *UnitSubscriber) {
+	w := watcher
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.remove(s)
+
+	if len(w.subscribers) == 0 && w.conn != nil {
+		w.shutdown()
+	}
+}
+
+// start must be called with the lock held
+func (w *unitWatcher) start() error {
+	conn, err := sd.New()
+	if err != nil {
+		log.Errorf("Failed to get systemd bus connection: %s", err)
+		return err
+	}
+
+	err = conn.Subscribe()
+	if err != nil {
+		conn.Close()
+		log.Errorf("Failed to subscribe to systemd signals: %s", err)
+		return err
+	}
+
+	units, err := conn.ListUnits()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	w.units = make(map[string]unitState, len(units))
+	for _, u := range units {
+		w.units[u.Name] = unitState{LoadState: u.LoadState, ActiveState: u.ActiveState, SubState: u.SubState}
+	}
+
+	updates := make(chan *sd.PropertiesUpdate, eventsBuffer)
+	errs := make(chan error, 1)
+	conn.SetPropertiesSubscriber(updates, errs)
+
+	w.conn = conn
+	w.stop = make(chan struct{})
+
+	go w.run(conn, w.stop, updates, errs)
+
+	return nil
+}
+
+// shutdown must be called with the lock held
+func (w *unitWatcher) shutdown() {
+	close(w.stop)
+	w.conn.Close()
+	w.conn = nil
+
+	for s := range w.subscribers {
+		w.remove(s)
+	}
+}
+
+func (w *unitWatcher) remove(s *UnitSubscriber) {
+	if _, ok := w.subscribers[s]; !ok {
+		return
+	}
+
+	delete(w.subscribers, s)
+	close(s.events)
+}
+
+func (w *unitWatcher) run(conn *sd.Conn, stop chan struct{}, updates chan *sd.PropertiesUpdate, errs chan error) {
+	ticker := time.NewTicker(eventsPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case u := <-updates:
+			w.changed(u)
+		case err := <-errs:
+			log.Errorf("Lost systemd unit signals: %s", err)
+		case <-ticker.C:
+			units, err := conn.ListUnits()
+			if err != nil {
+				log.Errorf("Failed to list units: %s", err)
+				continue
+			}
+
+			w.poll(units)
+		}
+	}
+}
+
+// changed applies a PropertiesChanged signal
+func (w *unitWatcher) changed(u *sd.PropertiesUpdate) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.conn == nil {
+		return
+	}
+
+	old, known := w.units[u.UnitName]
+	cur := old
+
+	if v, ok := u.Changed["LoadState"]; ok {
+		cur.LoadState, _ = v.Value().(string)
+	}
+
+	if v, ok := u.Changed["ActiveState"]; ok {
+		cur.ActiveState, _ = v.Value().(string)
+	}
+
+	if v, ok := u.Changed["SubState"]; ok {
+		cur.SubState, _ = v.Value().(string)
+	}
+
+	w.update(u.UnitName, old, cur, known)
+}
+
+// poll catches the units that appeared or went away since the last poll
+func (w *unitWatcher) poll(units []sd.UnitStatus) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.conn == nil {
+		return
+	}
+
+	seen := make(map[string]struct{}, len(units))
+	for _, u := range units {
+		seen[u.Name] = struct{}{}
+
+		old, known := w.units[u.Name]
+		w.update(u.Name, old, unitState{LoadState: u.LoadState, ActiveState: u.ActiveState, SubState: u.SubState}, known)
+	}
+
+	for name, old := range w.units {
+		if _, ok := seen[name]; ok {
+			continue
+		}
+
+		delete(w.units, name)
+		w.publish(&UnitEvent{
+			Type:           UnitEventRemoved,
+			Unit:           name,
+			OldActiveState: old.ActiveState,
+			OldSubState:    old.SubState,
+			Time:           time.Now().UTC(),
+		})
+	}
+}
+
+// update must be called with the lock held
+func (w *unitWatcher) update(name string, old unitState, cur unitState, known bool) {
+	if known && old == cur {
+		return
+	}
+
+	w.units[name] = cur
+
+	e := &UnitEvent{
+		Type:        UnitEventChanged,
+		Unit:        name,
+		LoadState:   cur.LoadState,
+		ActiveState: cur.ActiveState,
+		SubState:    cur.SubState,
+		Time:        time.Now().UTC(),
+	}
+
+	if known {
+		e.OldActiveState = old.ActiveState
+		e.OldSubState = old.SubState
+	} else {
+		e.Type = UnitEventNew
+	}
+
+	w.publish(e)
+}
+
+// publish must be called with the lock held. A subscriber whose buffer is
+// full is dropped rather than stalling everybody else
+func (w *unitWatcher) publish(e *UnitEvent) {
+	for s := range w.subscribers {
+		if !s.matches(e.Unit) {
+			continue
+		}
+
+		select {
+		case s.events <- e:
+		default:
+			log.Errorf("Dropping systemd events subscriber lagging behind")
+			w.remove(s)
+		}
+	}
+}
+
+//StreamUnitEvents pushes unit events to the client until it goes away
+func StreamUnitEvents(w http.ResponseWriter, r *http.Request) error {
+	globs := r.URL.Query()["unit"]
+	for _, g := range globs {
+		if !ValidUnitGlob(g) {
+			return share.NewError(http.StatusBadRequest, "Invalid unit glob '%s'", g)
+		}
+	}
+
+	sub, err := SubscribeUnits(globs)
+	if err != nil {
+		return err
+	}
+	defer UnsubscribeUnits(sub)
+
+	stream, err := share.NewEventStream(w, r)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	keepalive := time.NewTicker(share.StreamKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-stream.Done():
+			return nil
+		case e, ok := <-sub.Events:
+			if !ok {
+				return nil
+			}
+
+			err = stream.Send(e.Type, e)
+		case <-keepalive.C:
+			err = stream.Ping()
+		}
+
+		if err != nil {
+			return nil
+		}
+	}
+}
@@ -3,10 +3,10 @@
 package systemd
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/RestGW/api-routerd/cmd/share"
 
@@ -15,6 +15,12 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	// longest wait for a start, stop or restart job. systemd cancels jobs
+	// after TimeoutStartSec, which may be infinity
+	unitJobTimeout = 5 * time.Minute
+)
+
 //Unit JSON message
 type Unit struct {
 	Action   string `json:"action"`
@@ -30,12 +36,18 @@ type Property struct {
 	Value    string `json:"value"`
 }
 
-//UnitStatus unit status
+//UnitStatus unit status as served by v1, which names Status property
 type UnitStatus struct {
 	Status string `json:"property"`
 	Unit   string `json:"unit"`
 }
 
+//UnitState unit status as served by v2
+type UnitState struct {
+	Status string `json:"status"`
+	Unit   string `json:"unit"`
+}
+
 //State sytemd state
 func State(w http.ResponseWriter) error {
 	v, err := getProperty("SystemState")
@@ -111,16 +123,31 @@ func Features(w http.ResponseWriter) error {
 	return share.JSONResponse(prop, w)
 }
 
+//FailedUnits number of units in failed state
+func FailedUnits() (uint32, error) {
+	v, err := getProperty("NFailedUnits")
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := v.Value().(uint32)
+	if !ok {
+		return 0, fmt.Errorf("Unexpected NFailedUnits value '%v'", v.Value())
+	}
+
+	return n, nil
+}
+
 //NFailedUnits how many uniuts failed
 func NFailedUnits(w http.ResponseWriter) error {
-	v, err := getProperty("NFailedUnits")
+	n, err := FailedUnits()
 	if err != nil {
 		return err
 	}
 
 	prop := Property{
 		Property: "NFailedUnits",
-		Value:    fmt.Sprint(v.Value().(uint32)),
+		Value:    fmt.Sprint(n),
 	}
 
 	return share.JSONResponse(prop, w)
@@ -159,61 +186,85 @@ func ListUnits(w http.ResponseWriter) error {
 	return share.JSONResponse(units, w)
 }
 
-//StartUnit start a unit
-func (u *Unit) StartUnit() error {
+// jobResult fails unless the systemd job result is done. Others are
+// canceled, timeout, failed, dependency and skipped
+func jobResult(unit string, action string, result string) (string, error) {
+	if result != "done" {
+		return result, fmt.Errorf("Job to %s unit '%s' finished with result '%s'", action, unit, result)
+	}
+
+	return result, nil
+}
+
+// waitJob the result of the job to act on unit, an error if systemd does not
+// finish it in unitJobTimeout
+func waitJob(unit string, action string, reschan <-chan string) (string, error) {
+	t := time.NewTimer(unitJobTimeout)
+	defer t.Stop()
+
+	select {
+	case result := <-reschan:
+		return jobResult(unit, action, result)
+	case <-t.C:
+		return "", fmt.Errorf("Job to %s unit '%s' did not finish in %s", action, unit, unitJobTimeout)
+	}
+}
+
+//StartUnit start a unit and wait for the systemd job
+func (u *Unit) StartUnit() (string, error) {
 	conn, err := sd.NewSystemdConnection()
 	if err != nil {
 		log.Errorf("Failed to get systemd bus connection: %v", err)
-		return err
+		return "", err
 	}
 	defer conn.Close()
 
-	reschan := make(chan string)
+	reschan := make(chan string, 1)
 	_, err = conn.StartUnit(u.Unit, "replace", reschan)
 	if err != nil {
 		log.Errorf("Failed to start unit %s: %v", u.Unit, err)
-		return err
+		return "", err
 	}
 
-	return nil
+	return waitJob(u.Unit, "start", reschan)
 }
 
-//StopUnit stop a unit
-func (u *Unit) StopUnit() error {
+//StopUnit stop a unit and wait for the systemd job
+func (u *Unit) StopUnit() (string, error) {
 	conn, err := sd.NewSystemdConnection()
 	if err != nil {
 		log.Errorf("Failed to get systemd bus connection: %s", err)
-		return err
+		return "", err
 	}
 	defer conn.Close()
 
-	reschan := make(chan string)
+	reschan := make(chan string, 1)
 	_, err = conn.StopUnit(u.Unit, "fail", reschan)
 	if err != nil {
 		log.Errorf("Failed to stop unit %s: %v", u.Unit, err)
-		return err
+		return "", err
 	}
 
-	return nil
+	return waitJob(u.Unit, "stop", reschan)
 }
 
-//RestartUnit restart a unit
-func (u *Unit) RestartUnit() error {
+//RestartUnit restart a unit and wait for the systemd job
+func (u *Unit) RestartUnit() (string, error) {
 	conn, err := sd.NewSystemdConnection()
 	if err != nil {
 		log.Errorf("Failed to get systemd bus connection: %v", err)
-		return err
+		return "", err
 	}
 	defer conn.Close()
 
-	reschan := make(chan string)
+	reschan := make(chan string, 1)
 	_, err = conn.RestartUnit(u.Unit, "replace", reschan)
 	if err != nil {
 		log.Errorf("Failed to restart unit %s: %v", u.Unit, err)
-		return err
+		return "", err
 	}
 
-	return nil
+	return waitJob(u.Unit, "restart", reschan)
 }
 
 //ReloadUnit reload daemon
@@ -246,7 +297,7 @@ func (u *Unit) KillUnit() error {
 	signal, err := strconv.ParseInt(u.Value, 10, 64)
 	if err != nil {
 		log.Errorf("Failed to parse signal number '%s': %s", u.Value, err)
-		return err
+		return share.BadRequest(fmt.Errorf("Failed to parse signal number '%s'", u.Value))
 	}
 
 	conn.KillUnit(u.Unit, int32(signal))
@@ -254,8 +305,8 @@ func (u *Unit) KillUnit() error {
 	return nil
 }
 
-//GetUnitStatus get unit status
-func (u *Unit) GetUnitStatus(w http.ResponseWriter) error {
+//GetUnitStatus get unit status in the shape of the API version
+func (u *Unit) GetUnitStatus(w http.ResponseWriter, version string) error {
 	conn, err := sd.NewSystemdConnection()
 	if err != nil {
 		log.Errorf("Failed to get systemd bus connection: %v", err)
@@ -269,14 +320,25 @@ func (u *Unit) GetUnitStatus(w http.ResponseWriter) error {
 		return err
 	}
 
-	status := UnitStatus{
+	if len(units) == 0 || units[0].LoadState == "not-found" {
+		return share.NotFound(fmt.Errorf("Unit '%s' not found", u.Unit))
+	}
+
+	if version == share.APIv1 {
+		status := UnitStatus{
+			Status: units[0].ActiveState,
+			Unit:   u.Unit,
+		}
+
+		return share.JSONResponse(status, w)
+	}
+
+	status := UnitState{
 		Status: units[0].ActiveState,
 		Unit:   u.Unit,
 	}
 
-	json.NewEncoder(w).Encode(status)
-
-	return nil
+	return share.JSONResponse(status, w)
 }
 
 //GetUnitProperty get unit property
@@ -327,7 +389,7 @@ func (u *Unit) SetUnitProperty(w http.ResponseWriter) error {
 		n, err := strconv.ParseInt(u.Value, 10, 64)
 		if err != nil {
 			log.Errorf("Failed to parse CPUShares: %v", err)
-			return err
+			return share.BadRequest(fmt.Errorf("Failed to parse CPUShares '%s'", u.Value))
 		}
 
 		p := sd.Property{
@@ -340,6 +402,8 @@ func (u *Unit) SetUnitProperty(w http.ResponseWriter) error {
 			return err
 		}
 		break
+	default:
+		return share.BadRequest(fmt.Errorf("Setting property '%s' is not supported", u.Property))
 	}
 
 	return nil
@@ -3,12 +3,9 @@
 package systemd
 
 import (
-	"bufio"
 	"encoding/json"
-	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
 
 	"github.com/RestGW/api-routerd/cmd/share"
 
@@ -74,17 +71,10 @@ var systemConfig = map[string]string{
 	"IPAddressDeny":                "",
 }
 
-func writeSystemConfig() error {
-	f, err := os.OpenFile(systemConfPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	w := bufio.NewWriter(f)
-
+func writeSystemConfig(r *http.Request) error {
 	conf := "[Manager]\n"
-	for k, v := range systemConfig {
+	for _, k := range share.SortedKeys(systemConfig) {
+		v := systemConfig[k]
 		if v != "" {
 			conf += k + "=" + v
 		} else {
@@ -93,10 +83,7 @@ func writeSystemConfig() error {
 		conf += "\n"
 	}
 
-	fmt.Fprintln(w, conf)
-	w.Flush()
-
-	return nil
+	return share.WriteConfigFile(r, systemConfPath, conf+"\n", 0644)
 }
 
 func readSystemConf() error {
@@ -134,7 +121,7 @@ func UpdateSystemConf(rw http.ResponseWriter, r *http.Request) error {
 	err = json.Unmarshal([]byte(body), &conf)
 	if err != nil {
 		log.Errorf("Failed to Decode HTTP request to json: %v", err)
-		return err
+		return share.BadRequest(err)
 	}
 
 	err = readSystemConf()
@@ -149,7 +136,7 @@ func UpdateSystemConf(rw http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
-	err = writeSystemConfig()
+	err = writeSystemConfig(r)
 	if err != nil {
 		log.Errorf("Failed Write to system conf: %v", err)
 		return err
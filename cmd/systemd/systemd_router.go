@@ -6,6 +6,10 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/RestGW/api-routerd/cmd/jobs"
+	"github.com/RestGW/api-routerd/cmd/share"
+
+	sd "github.com/coreos/go-systemd/dbus"
 	"github.com/gorilla/mux"
 )
 
@@ -14,9 +18,11 @@ func routerGetSystemdState(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := State(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -25,9 +31,11 @@ func routerGetSystemdVersion(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := Version(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -36,9 +44,11 @@ func routerGetSystemdFeatures(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := Features(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -47,9 +57,11 @@ func routerGetSystemdVirtualization(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := Virtualization(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -58,9 +70,11 @@ func routerGetSystemdNFailedUnits(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := NFailedUnits(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -69,9 +83,11 @@ func routerGetSystemdNNames(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := NNames(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -80,9 +96,11 @@ func routerGetSystemdArchitecture(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := Architecture(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -91,16 +109,18 @@ func routerConfigureSystemdConf(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := GetSystemConf(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
 
 	case "POST":
 		err := UpdateSystemConf(rw, r)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -113,19 +133,19 @@ func routerConfigureUnit(rw http.ResponseWriter, r *http.Request) {
 
 		err = json.NewDecoder(r.Body).Decode(&unit)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, share.BadRequest(err))
 			return
 		}
 
 		switch unit.Action {
 		case "start":
-			err = unit.StartUnit()
+			err = jobs.Run(rw, r, "start "+unit.Unit, unit.StartUnit)
 			break
 		case "stop":
-			err = unit.StopUnit()
+			err = jobs.Run(rw, r, "stop "+unit.Unit, unit.StopUnit)
 			break
 		case "restart":
-			err = unit.RestartUnit()
+			err = jobs.Run(rw, r, "restart "+unit.Unit, unit.RestartUnit)
 			break
 		case "reload":
 			err = unit.ReloadUnit()
@@ -133,12 +153,17 @@ func routerConfigureUnit(rw http.ResponseWriter, r *http.Request) {
 		case "kill":
 			err = unit.KillUnit()
 			break
+		default:
+			err = share.NewError(http.StatusBadRequest, "Unknown action '%s'", unit.Action)
 		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
+		return
 	}
 
 	if err != nil {
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		share.HTTPError(rw, err)
 	}
 }
 
@@ -147,10 +172,12 @@ func routerGetAllSystemdUnits(rw http.ResponseWriter, r *http.Request) {
 	case "GET":
 		err := ListUnits(rw)
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -164,12 +191,14 @@ func routerGetUnitStatus(rw http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "GET":
-		err := u.GetUnitStatus(rw)
+		err := u.GetUnitStatus(rw, share.APIVersion(r))
 		if err != nil {
-			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			share.HTTPError(rw, err)
 		}
 
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -185,8 +214,13 @@ func routerGetUnitProperty(rw http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "GET":
-		u.GetUnitProperty(rw)
+		err := u.GetUnitProperty(rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -195,20 +229,25 @@ func routerConfigureUnitProperty(rw http.ResponseWriter, r *http.Request) {
 	unit := vars["unit"]
 	property := vars["property"]
 
-	u := new(Unit)
-	err := json.NewDecoder(r.Body).Decode(&u)
-	if err != nil {
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	u.Unit = unit
-	u.Property = property
-
 	switch r.Method {
 	case "PUT":
-		u.SetUnitProperty(rw)
+		u := new(Unit)
+		err := json.NewDecoder(r.Body).Decode(&u)
+		if err != nil {
+			share.HTTPError(rw, share.BadRequest(err))
+			return
+		}
+
+		u.Unit = unit
+		u.Property = property
+
+		err = u.SetUnitProperty(rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -226,8 +265,26 @@ func routerGetUnitTypeProperty(rw http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "GET":
-		u.GetUnitTypeProperty(rw)
+		err := u.GetUnitTypeProperty(rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+func routerGetUnitEvents(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		err := StreamUnitEvents(rw, r)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
 		break
+	default:
+		share.MethodNotAllowed(rw, r)
 	}
 }
 
@@ -236,24 +293,46 @@ func RegisterRouterSystemd(router *mux.Router) {
 	n := router.PathPrefix("/service").Subrouter()
 
 	// property
-	n.HandleFunc("/systemd/state", routerGetSystemdState)
-	n.HandleFunc("/systemd/version", routerGetSystemdVersion)
-	n.HandleFunc("/systemd/features", routerGetSystemdFeatures)
-	n.HandleFunc("/systemd/virtualization", routerGetSystemdVirtualization)
-	n.HandleFunc("/systemd/architecture", routerGetSystemdArchitecture)
-	n.HandleFunc("/systemd/units", routerGetAllSystemdUnits)
-	n.HandleFunc("/systemd/nnames", routerGetSystemdNNames)
-	n.HandleFunc("/systemd/nfailedunits", routerGetSystemdNFailedUnits)
+	share.Describe(n.HandleFunc("/systemd/state", routerGetSystemdState),
+		share.Op("GET", "systemd SystemState", nil, Property{}))
+	share.Describe(n.HandleFunc("/systemd/version", routerGetSystemdVersion),
+		share.Op("GET", "systemd Version", nil, Property{}))
+	share.Describe(n.HandleFunc("/systemd/features", routerGetSystemdFeatures),
+		share.Op("GET", "systemd Features", nil, Property{}))
+	share.Describe(n.HandleFunc("/systemd/virtualization", routerGetSystemdVirtualization),
+		share.Op("GET", "systemd Virtualization", nil, Property{}))
+	share.Describe(n.HandleFunc("/systemd/architecture", routerGetSystemdArchitecture),
+		share.Op("GET", "systemd Architecture", nil, Property{}))
+	share.Describe(n.HandleFunc("/systemd/units", routerGetAllSystemdUnits),
+		share.Op("GET", "List units", nil, []sd.UnitStatus{}))
+	share.Describe(n.HandleFunc("/systemd/nnames", routerGetSystemdNNames),
+		share.Op("GET", "systemd NNames", nil, Property{}))
+	share.Describe(n.HandleFunc("/systemd/nfailedunits", routerGetSystemdNFailedUnits),
+		share.Op("GET", "systemd NFailedUnits", nil, Property{}))
+
+	share.Describe(n.HandleFunc("/systemd/events", routerGetUnitEvents),
+		share.Op("GET", "Stream unit state changes over SSE or WebSocket, filtered by ?unit=<glob>", nil, UnitEvent{}))
 
 	// unit
-	n.HandleFunc("/systemd", routerConfigureUnit)
-	n.HandleFunc("/systemd/{unit}/status", routerGetUnitStatus)
-	n.HandleFunc("/systemd/{unit}/get", routerGetUnitProperty)
-	n.HandleFunc("/systemd/{unit}/get/{property}", routerGetUnitProperty)
-	n.HandleFunc("/systemd/{unit}/set/{property}", routerConfigureUnitProperty)
-	n.HandleFunc("/systemd/{unit}/gettype/{unittype}", routerGetUnitTypeProperty)
+	share.Describe(n.HandleFunc("/systemd", routerConfigureUnit),
+		share.Op("POST", "start, stop, restart, reload or kill a unit. start, stop and restart reply 202 with a job", Unit{}, jobs.Job{}))
+	share.Describe(n.HandleFunc("/systemd/{unit}/status", routerGetUnitStatus),
+		share.Op("GET", "Active state of a unit", nil, UnitStatus{}).For(share.APIv1),
+		share.Op("GET", "Active state of a unit", nil, UnitState{}).For(share.APIv2))
+	share.Describe(n.HandleFunc("/systemd/{unit}/get", routerGetUnitProperty),
+		share.Op("GET", "All properties of a unit", nil, map[string]interface{}{}))
+	share.Describe(n.HandleFunc("/systemd/{unit}/get/{property}", routerGetUnitProperty),
+		share.Op("GET", "A property of a unit", nil, Property{}))
+	share.Describe(n.HandleFunc("/systemd/{unit}/set/{property}", routerConfigureUnitProperty),
+		share.Op("PUT", "Set a property of a unit", Unit{}, nil))
+	share.Describe(n.HandleFunc("/systemd/{unit}/gettype/{unittype}", routerGetUnitTypeProperty),
+		share.Op("GET", "Properties of a unit type", nil, map[string]interface{}{}))
 
 	// conf
-	n.HandleFunc("/systemd/conf", routerConfigureSystemdConf)
-	n.HandleFunc("/systemd/conf/update", routerConfigureSystemdConf)
+	share.Describe(n.HandleFunc("/systemd/conf", routerConfigureSystemdConf),
+		share.Op("GET", "Read system.conf", nil, map[string]string{}),
+		share.Op("POST", "Update system.conf", map[string]string{}, map[string]string{}).Previewable())
+	share.Describe(n.HandleFunc("/systemd/conf/update", routerConfigureSystemdConf),
+		share.Op("GET", "Read system.conf", nil, map[string]string{}),
+		share.Op("POST", "Update system.conf", map[string]string{}, map[string]string{}).Previewable())
 }
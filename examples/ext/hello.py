@@ -0,0 +1,37 @@
+#!/usr/bin/env python3
+# SPDX-License-Identifier: Apache-2.0
+#
+# hello as an ext plugin mounted under /api/ext/hello. Copy it to
+# /etc/api-routerd/ext/ and make it executable.
+
+import json
+import sys
+
+
+def send(message):
+    sys.stdout.write(json.dumps(message) + "\n")
+    sys.stdout.flush()
+
+
+def handle(request):
+    if request["route"] == "/sayhello/{text}":
+        return {"status": 200, "body": {"text": request["vars"]["text"], "user": request.get("identity")}}
+
+    return {"status": 404, "error": "No route for " + request["path"]}
+
+
+send({
+    "protocol": 1,
+    "version": "0.1",
+    "routes": [
+        {"method": "GET", "path": "/sayhello/{text}", "summary": "Say hello"},
+    ],
+})
+
+for line in sys.stdin:
+    request = json.loads(line)
+
+    response = handle(request)
+    response["id"] = request["id"]
+
+    send(response)
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// The hello example as a Go plugin mounted under /api/plugins/hello. Build it from the
+// api-routerd tree so it links the same packages:
+//
+//	go build -buildmode=plugin -o /etc/api-routerd/plugins/hello.so ./examples/plugin/hello
+package main
+
+import (
+	"net/http"
+
+	"github.com/RestGW/api-routerd/cmd/share"
+	hello "github.com/RestGW/api-routerd/examples/plugin"
+
+	"github.com/gorilla/mux"
+)
+
+//Version reported by /api/plugins
+var Version = "0.1"
+
+func routerSayHello(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		g := hello.Hello{
+			Text: mux.Vars(r)["text"],
+		}
+
+		err := g.SayHello(rw)
+		if err != nil {
+			share.HTTPError(rw, err)
+		}
+		break
+	default:
+		share.MethodNotAllowed(rw, r)
+	}
+}
+
+//Register called by api-routerd with the /api/plugins/hello router
+func Register(router *mux.Router) {
+	router.HandleFunc("/sayhello/{text}", routerSayHello)
+}
+
+// main is not called for -buildmode=plugin but keeps go build ./... working
+func main() {}
@@ -3,29 +3,80 @@
 package main
 
 import (
+	"bufio"
+	"fmt"
+	"io"
 	"os"
-	"path"
 	"runtime"
+	"strings"
 
 	"github.com/RestGW/api-routerd/cmd/conf"
 	"github.com/RestGW/api-routerd/cmd/router"
 	"github.com/RestGW/api-routerd/cmd/share"
 
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
+// hashSecret reads a secret from the command line or stdin and prints the hash for api-routerd-auth.conf
+func hashSecret(args []string, stdin *os.File, out io.Writer) error {
+	var secret string
+
+	if len(args) > 0 {
+		secret = args[0]
+	} else if terminal.IsTerminal(int(stdin.Fd())) {
+		fmt.Fprint(os.Stderr, "Secret: ")
+
+		b, err := terminal.ReadPassword(int(stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return err
+		}
+
+		secret = string(b)
+	} else {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return err
+		}
+
+		secret = strings.TrimRight(line, "\r\n")
+	}
+
+	h, err := router.HashSecret(secret)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, h)
+
+	return nil
+}
+
 func main() {
-	share.InitLog()
+	if len(os.Args) > 1 && os.Args[1] == "hash-secret" {
+		err := hashSecret(os.Args[2:], os.Stdin, os.Stdout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to hash secret: %s\n", err)
+			os.Exit(1)
+		}
+
+		os.Exit(0)
+	}
 
 	err := conf.InitConf()
 	if err != nil {
 		log.Errorf("Failed to init conf file %s: %s", conf.ConfFile, err)
 	}
 
+	c := conf.Current()
+
+	share.InitLog(&c.Log)
+
 	log.Infof("api-routerd: v%s (built %s)", conf.Version, runtime.Version())
-	log.Infof("Start Server at %s:%s", conf.IPFlag, conf.PortFlag)
+	log.Infof("Start Server at %s:%s", c.IPAddress, c.Port)
 
-	err = router.StartRouter(conf.IPFlag, conf.PortFlag, path.Join(conf.ConfPath, conf.TLSCert), path.Join(conf.ConfPath, conf.TLSKey))
+	err = router.StartRouter(c.IPAddress, c.Port, c.UnixSocket, c.Listeners, &c.TLS)
 	if err != nil {
 		log.Fatalf("Failed to init api-routerd: %v", err)
 		os.Exit(1)
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func checkHash(t *testing.T, out *bytes.Buffer, secret string) {
+	h := strings.TrimSpace(out.String())
+
+	if bcrypt.CompareHashAndPassword([]byte(h), []byte(secret)) != nil {
+		t.Errorf("hash '%s' does not match '%s'", h, secret)
+	}
+}
+
+func TestHashSecretArgument(t *testing.T) {
+	var out bytes.Buffer
+
+	err := hashSecret([]string{"s3cret"}, os.Stdin, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkHash(t, &out, "s3cret")
+}
+
+func TestHashSecretStdin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stdin")
+
+	err := ioutil.WriteFile(path, []byte("piped secret\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdin, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+
+	var out bytes.Buffer
+
+	err = hashSecret(nil, stdin, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkHash(t, &out, "piped secret")
+}
+
+func TestHashSecretEmpty(t *testing.T) {
+	var out bytes.Buffer
+
+	err := hashSecret([]string{""}, os.Stdin, &out)
+	if err == nil {
+		t.Errorf("empty secret was hashed to '%s'", out.String())
+	}
+}